@@ -0,0 +1,96 @@
+// artifact/artifact.go
+package artifact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jesee-kuya/LightGBM/booster"
+	"github.com/jesee-kuya/LightGBM/preprocess"
+)
+
+// Format identifies model artifacts written by this package.
+const Format = "lightgbm-go-model"
+
+// Version is the artifact layout version written by Save.
+const Version = 1
+
+// file is the on-disk layout: a header plus the booster and preprocessor
+// in their own versioned formats.
+type file struct {
+	Format       string
+	Version      int
+	Booster      json.RawMessage
+	Preprocessor json.RawMessage
+}
+
+// Save writes a trained booster and the preprocessor it was trained with
+// to w as a single versioned artifact.
+func Save(w io.Writer, b *booster.Booster, p *preprocess.Preprocessor) error {
+	var bb, pb bytes.Buffer
+	if err := b.Save(&bb); err != nil {
+		return err
+	}
+	if err := p.Save(&pb); err != nil {
+		return err
+	}
+	f := file{
+		Format:       Format,
+		Version:      Version,
+		Booster:      bb.Bytes(),
+		Preprocessor: pb.Bytes(),
+	}
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		return fmt.Errorf("artifact: save: %w", err)
+	}
+	return nil
+}
+
+// Load reads an artifact written by Save.
+func Load(r io.Reader) (*booster.Booster, *preprocess.Preprocessor, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, nil, fmt.Errorf("artifact: load: %w", err)
+	}
+	if f.Format != Format {
+		return nil, nil, fmt.Errorf("artifact: load: not a model artifact (format %q)", f.Format)
+	}
+	if f.Version < 1 || f.Version > Version {
+		return nil, nil, fmt.Errorf("artifact: load: unsupported version %d", f.Version)
+	}
+	b, err := booster.Load(bytes.NewReader(f.Booster))
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := preprocess.Load(bytes.NewReader(f.Preprocessor))
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, p, nil
+}
+
+// SaveFile writes the artifact to path, replacing any existing file.
+func SaveFile(path string, b *booster.Booster, p *preprocess.Preprocessor) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Save(f, b, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile reads the artifact stored at path.
+func LoadFile(path string) (*booster.Booster, *preprocess.Preprocessor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
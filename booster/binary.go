@@ -0,0 +1,61 @@
+// booster/binary.go
+package booster
+
+import "math"
+
+// newBinary is the log loss for labels in {0, 1} (any label > 0 counts as
+// positive), boosted on the log-odds.
+func newBinary(p ObjectiveParams) (Objective, error) {
+	return &scalarObjective{
+		name:   "binary",
+		params: p,
+		init: func(labels, weights []float64) float64 {
+			var pos, total float64
+			for i, y := range labels {
+				w := weight(weights, i)
+				if y > 0 {
+					pos += w
+				}
+				total += w
+			}
+			if total == 0 {
+				return 0
+			}
+			return logOdds(pos / total)
+		},
+		gradient: func(y, s float64) (float64, float64) {
+			label := 0.0
+			if y > 0 {
+				label = 1
+			}
+			p := sigmoid(s)
+			return p - label, p * (1 - p)
+		},
+		transform: sigmoid,
+	}, nil
+}
+
+// newCrossEntropy is the log loss for probabilistic labels in [0, 1].
+func newCrossEntropy(p ObjectiveParams) (Objective, error) {
+	return &scalarObjective{
+		name:   "cross_entropy",
+		params: p,
+		init: func(labels, weights []float64) float64 {
+			return logOdds(mean(labels, weights))
+		},
+		gradient: func(y, s float64) (float64, float64) {
+			p := sigmoid(s)
+			return p - y, p * (1 - p)
+		},
+		transform: sigmoid,
+	}, nil
+}
+
+func sigmoid(v float64) float64 {
+	return 1 / (1 + math.Exp(-v))
+}
+
+func logOdds(p float64) float64 {
+	p = min(max(p, 1e-15), 1-1e-15)
+	return math.Log(p / (1 - p))
+}
@@ -2,87 +2,604 @@
 package booster
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+
 	"github.com/jesee-kuya/LightGBM/tree"
 )
 
 // Booster trains one histogram-based tree ensemble per target column.
 type Booster struct {
-	Trees        [][]*tree.Node 
+	Trees        [][]*tree.Node
 	LearningRate float64
 	MaxDepth     int
-	MinSamples   int 
-	NumBins      int 
+	MinSamples   int
+	NumBins      int
+
+	// LeafWise switches from depth-wise growth to best-first growth, which
+	// keeps splitting the highest-gain leaf until a tree has NumLeaves
+	// leaves. MaxDepth still caps leaf-wise trees when it is > 0.
+	LeafWise  bool
+	NumLeaves int
+
+	// Regularization, see tree.Params: L1/L2 penalties on leaf outputs,
+	// the gain a split must exceed, the hessian sum each child must reach
+	// and a cap on absolute leaf outputs (0 = none).
+	LambdaL1            float64
+	LambdaL2            float64
+	MinGainToSplit      float64
+	MinSumHessianInLeaf float64
+	MaxDeltaStep        float64
+
+	// CategoricalFeatures lists the columns of X holding integer category
+	// codes, which are split into sets of categories rather than at a
+	// threshold; NaN, negative codes and codes above math.MaxInt32 are
+	// missing. The Cat* settings and MinDataPerGroup tune those splits,
+	// see tree.Params.
+	CategoricalFeatures []int
+	MaxCatToOnehot      int
+	MaxCatThreshold     int
+	CatSmooth           float64
+	CatL2               float64
+	MinDataPerGroup     int
+
+	// MonotoneConstraints[j] is +1 (-1) to force predictions to be
+	// non-decreasing (non-increasing) in feature j, or 0 for none; it is
+	// either empty or has one entry per feature. Constraints on
+	// categorical features are ignored.
+	MonotoneConstraints []int
+
+	// InteractionConstraints lists groups of feature indices; when set,
+	// every branch of a tree only splits on features of one group, and
+	// features in no group are not used. See tree.Params.
+	InteractionConstraints [][]int
+
+	// LinearTree gives every leaf a ridge regression on the numerical
+	// features split on along its path, with LinearLambda as the L2
+	// penalty on its coefficients; rows with NaN in one of those features
+	// get the leaf's constant value. It cannot be combined with
+	// MonotoneConstraints.
+	LinearTree   bool
+	LinearLambda float64
+
+	// Every BaggingFreq rounds (0 = never) each target draws a
+	// BaggingFraction share of the rows to fit its next trees on.
+	// FeatureFraction and FeatureFractionByNode sample the features of
+	// each tree and each split, see tree.Params. All sampling is driven by
+	// per-target RNGs seeded from Seed, so equal seeds give equal models.
+	BaggingFraction       float64
+	BaggingFreq           int
+	FeatureFraction       float64
+	FeatureFractionByNode float64
+	Seed                  int64
+
+	// ExtraTrees makes every split try one random threshold per feature,
+	// drawn from the same per-target RNGs, instead of every bin boundary.
+	ExtraTrees bool
+
+	// NumThreads bounds the goroutines Fit uses to train targets side by
+	// side and, within a target, to build histograms and find splits;
+	// 0 means runtime.GOMAXPROCS. Models are identical for any value.
+	// Objectives and metrics shared by several targets must be safe for
+	// concurrent use.
+	NumThreads int
+
+	// GOSS replaces bagging with gradient-based one-side sampling: each
+	// tree is fitted to the TopRate share of rows with the largest
+	// gradients plus a random OtherRate share of the rest, up-weighted to
+	// compensate. Sampling starts after the first 1/LearningRate rounds.
+	GOSS      bool
+	TopRate   float64
+	OtherRate float64
+
+	// Boosting is "gbdt" (plain gradient boosting), "dart" or "rf". In
+	// "rf" mode every tree is fitted to the gradients at the init scores on
+	// its own row sample (see forestRows) and feature subsets, and the
+	// trees are averaged instead of summed with LearningRate. DART leaves
+	// a random set of earlier rounds out when computing each round's
+	// gradients (see dropRounds for DropRate, MaxDrop and SkipDrop, drawn
+	// from an RNG seeded from DropSeed), then shrinks the new trees by
+	// 1/(n+1) and the n dropped ones by n/(n+1). Since DART keeps
+	// rescaling old trees, BestIteration is only approximate for it.
+	Boosting string
+	DropRate float64
+	MaxDrop  int
+	SkipDrop float64
+	DropSeed int64
+
+	// Objectives[j] is the loss target j is boosted against; NewBooster
+	// defaults every target to "regression" (squared error).
+	Objectives []Objective
+
+	// BoostFromAverage starts each target from its objective's InitScore
+	// (e.g. the label mean) instead of zero. InitScores[j] records the
+	// starting score of each model of target j.
+	BoostFromAverage bool
+	InitScores       [][]float64
+
+	// Metrics names the metrics evaluated on validation sets for every
+	// target; when empty each target uses its objective's default metric.
+	// EarlyStoppingRounds > 0 enables early stopping, which needs at least
+	// one validation set.
+	Metrics             []string
+	EarlyStoppingRounds int
+
+	// BestIteration[j] is the number of rounds Predict uses for target j
+	// (0 = all trees). EvalResults[j][set][metric] holds the value of each
+	// metric on each validation set after every round.
+	BestIteration []int
+	EvalResults   []map[string]map[string][]float64
 
 	NumTargets int
+	// NumFeatures is the width of the X the booster was trained on.
+	// FeatureNames optionally names each column, for FeatureImportance.
+	NumFeatures  int
+	FeatureNames []string
+
+	// Imported marks boosters read by LoadLightGBM. Their leaf values
+	// already include the shrinkage of the training that produced them and
+	// their training parameters are not restored, so Continue and Refit
+	// refuse them; Fit trains a new model and clears the mark.
+	Imported bool
 }
 
 // NewBooster allocates a Booster for `numTargets` outputs.
 // Uses `defaultBins` as the histogram bin count.
 func NewBooster(numTargets int, lr float64, maxDepth, minSamples, defaultBins int) *Booster {
 	trees := make([][]*tree.Node, numTargets)
+	objs := make([]Objective, numTargets)
+	for j := range objs {
+		objs[j], _ = newL2(ObjectiveParams{})
+	}
 	return &Booster{
 		Trees:        trees,
+		Objectives:   objs,
 		LearningRate: lr,
 		MaxDepth:     maxDepth,
 		MinSamples:   minSamples,
 		NumBins:      defaultBins,
-		NumTargets:   numTargets,
+		NumLeaves:    31,
+		LambdaL2:     1e-3,
+
+		MaxCatToOnehot:  4,
+		MaxCatThreshold: 32,
+		CatSmooth:       10,
+		CatL2:           10,
+		MinDataPerGroup: 100,
+
+		BaggingFraction:       1,
+		FeatureFraction:       1,
+		FeatureFractionByNode: 1,
+
+		TopRate:   0.2,
+		OtherRate: 0.1,
+
+		Boosting: "gbdt",
+		DropRate: 0.1,
+		MaxDrop:  50,
+		SkipDrop: 0.5,
+		DropSeed: 4,
+
+		NumTargets: numTargets,
 	}
 }
 
-// Fit trains `nRounds` of boosting; X is N×D, Y is N×T (T = numTargets).
-// Uses squared-error: gradient = pred - target, hessian = 1.
-func (b *Booster) Fit(X [][]float64, Y [][]float64, nRounds int) {
+// Fit trains up to `nRounds` of boosting; X is N×D, Y is N×T (T = numTargets).
+// X is binned once into a tree.Dataset with `NumBins` bins per numerical
+// feature and one bin per category of CategoricalFeatures.
+// Each round, target j gets one tree per model of Objectives[j], fitted to
+// that objective's gradients and hessians.
+//
+// WithWeights weights the rows and WithInitScore starts a target from
+// per-row scores; otherwise targets start from InitScores, which
+// BoostFromAverage sets to each objective's (weighted) base score.
+//
+// Validation sets added with WithValidSet are scored with each target's
+// metrics after every round (see EvalResults). With EarlyStoppingRounds > 0,
+// a target stops training once any of its metrics has not improved for that
+// many rounds, and BestIteration records its best round.
+//
+// Fit starts from an empty model; see Continue to add rounds to a trained one.
+func (b *Booster) Fit(X [][]float64, Y [][]float64, nRounds int, opts ...FitOption) error {
+	return b.train(X, Y, nRounds, false, opts)
+}
+
+// Continue resumes training: it drops any rounds past BestIteration, starts
+// every row of X from the current model's raw scores and appends up to
+// nRounds more rounds, as Fit would. InitScores are kept, and per-row init
+// scores given with WithInitScore are added on top of the model's scores.
+// BestIteration and EvalResults count the rounds already in the model.
+// Models loaded with LoadLightGBM cannot be resumed.
+// Row, feature and drop sampling are seeded past those rounds, so the new
+// rounds draw fresh samples.
+func (b *Booster) Continue(X [][]float64, Y [][]float64, nRounds int, opts ...FitOption) error {
+	return b.train(X, Y, nRounds, true, opts)
+}
+
+// train implements Fit and, with resume set, Continue.
+func (b *Booster) train(X [][]float64, Y [][]float64, nRounds int, resume bool, opts []FitOption) error {
+	var cfg fitConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if resume && b.Imported {
+		return fmt.Errorf("booster: continue: cannot resume a model loaded from the LightGBM format")
+	}
+	b.Imported = false
+
 	N := len(X)
 	T := b.NumTargets
+	dart := b.Boosting == "dart"
+	rf := b.Boosting == "rf"
+	if b.Boosting != "" && b.Boosting != "gbdt" && !dart && !rf {
+		return fmt.Errorf("booster: unknown boosting %q", b.Boosting)
+	}
+	if rf && b.GOSS {
+		return fmt.Errorf("booster: GOSS cannot be used with rf boosting")
+	}
+	if b.LinearTree && slices.ContainsFunc(b.MonotoneConstraints, func(c int) bool { return c != 0 }) {
+		return fmt.Errorf("booster: monotone constraints cannot be used with linear trees")
+	}
+	if b.EarlyStoppingRounds > 0 && len(cfg.valid) == 0 {
+		return fmt.Errorf("booster: early stopping needs a validation set, see WithValidSet")
+	}
+	if err := cfg.check(b, N); err != nil {
+		return err
+	}
 
-	// Initialize predictions ŷ to zero: preds[i][j]
-	preds := make([][]float64, N)
-	for i := range preds {
-		preds[i] = make([]float64, T)
+	metrics := make([][]Metric, T)
+	for j := range T {
+		var err error
+		if metrics[j], err = b.metricsFor(j); err != nil {
+			return err
+		}
 	}
 
-	for round := 0; round < nRounds; round++ {
-		for j := 0; j < T; j++ {
-			// Compute gradients and hessians for target j
-			grad := make([]float64, N)
-			hess := make([]float64, N)
-			for i := range N {
-				grad[i] = preds[i][j] - Y[i][j] // pred - target
-				hess[i] = 1.0
+	ds := tree.NewDataset(X, b.NumBins, b.CategoricalFeatures)
+	if resume && b.NumFeatures > 0 && b.NumFeatures != ds.NumFeatures {
+		return fmt.Errorf("booster: continue: model has %d features, X has %d", b.NumFeatures, ds.NumFeatures)
+	}
+	if m := len(b.MonotoneConstraints); m > 0 && m != ds.NumFeatures {
+		return fmt.Errorf("booster: %d monotone constraints for %d features", m, ds.NumFeatures)
+	}
+	for j, c := range b.MonotoneConstraints {
+		if c < -1 || c > 1 {
+			return fmt.Errorf("booster: monotone constraint %d of feature %d is not -1, 0 or 1", c, j)
+		}
+	}
+	for _, group := range b.InteractionConstraints {
+		for _, j := range group {
+			if j < 0 || j >= ds.NumFeatures {
+				return fmt.Errorf("booster: interaction constraint feature %d out of range [0,%d)", j, ds.NumFeatures)
+			}
+		}
+	}
+	b.NumFeatures = ds.NumFeatures
+	rows := make([]int, N)
+	for i := range rows {
+		rows[i] = i
+	}
+
+	// Initialize raw scores ŷ: preds[j][k][i] is the score of row i for
+	// model k (class k, or 0 for single-output objectives) of target j,
+	// starting from base[j][k][i]. A resumed model adds its trees' outputs.
+	labels := make([][]float64, T)
+	base := make([][][]float64, T)
+	preds := make([][][]float64, T)
+	offsets := make([]int, T)
+	if len(b.Trees) != T {
+		b.Trees = make([][]*tree.Node, T)
+	}
+	if len(b.InitScores) != T {
+		b.InitScores = make([][]float64, T)
+	}
+	for j := range T {
+		labels[j] = column(Y, j)
+		K := b.numModels(j)
+		if resume && len(b.InitScores[j]) == K {
+			b.Trees[j] = b.Trees[j][:b.numIterations(j)*K]
+			offsets[j] = len(b.Trees[j]) / K
+		} else {
+			// Fit, or a target with no model to resume from
+			b.Trees[j] = nil
+			b.InitScores[j] = make([]float64, K)
+			if _, ok := cfg.initScores[j]; !ok && b.BoostFromAverage {
+				b.InitScores[j] = b.objective(j).InitScore(labels[j], cfg.weights)
+			}
+		}
+		base[j] = initScores(b.InitScores[j], N)
+		if scores, ok := cfg.initScores[j]; ok {
+			for k := range K {
+				for i := range N {
+					base[j][k][i] += scores[i][k]
+				}
+			}
+		}
+		preds[j] = copyScores(base[j])
+		if resume {
+			b.addModelScores(j, preds[j], X)
+		}
+	}
+	for _, vs := range cfg.valid {
+		vs.labels = make([][]float64, T)
+		vs.base = make([][][]float64, T)
+		vs.scores = make([][][]float64, T)
+		for j := range T {
+			vs.labels[j] = column(vs.Y, j)
+			vs.base[j] = initScores(b.InitScores[j], len(vs.X))
+			vs.scores[j] = copyScores(vs.base[j])
+			if resume {
+				b.addModelScores(j, vs.scores[j], vs.X)
+			}
+		}
+	}
+
+	b.BestIteration = make([]int, T)
+	b.EvalResults = make([]map[string]map[string][]float64, T)
+	stoppers := make([]*earlyStopper, T)
+	for j := range T {
+		b.EvalResults[j] = make(map[string]map[string][]float64)
+		stoppers[j] = newEarlyStopper(len(cfg.valid), metrics[j])
+	}
+	stopped := make([]bool, T)
+
+	rngs := make([]*rand.Rand, T)
+	dropRngs := make([]*rand.Rand, T)
+	bags := make([][]int, T)
+	for j := range T {
+		// A resumed model seeds past the rounds it already has, so that
+		// Continue does not replay the draws of its first rounds
+		shift := int64(j) + int64(offsets[j])*int64(T)
+		rngs[j] = rand.New(rand.NewSource(b.Seed + shift))
+		dropRngs[j] = rand.New(rand.NewSource(b.DropSeed + shift))
+		bags[j] = rows
+	}
+
+	// Targets are independent, so each round trains them on up to
+	// targetThreads goroutines, sharing the rest of the threads out to
+	// their trees
+	threads := b.NumThreads
+	if threads <= 0 {
+		threads = runtime.GOMAXPROCS(0)
+	}
+	targetThreads := max(1, min(threads, T))
+	baseParams := b.treeParams()
+	baseParams.NumThreads = max(1, threads/targetThreads)
+
+	boostTarget := func(j, round int) {
+		params := baseParams
+		params.Rand = rngs[j]
+		if rf {
+			bags[j] = b.forestRows(N, rngs[j])
+		} else if b.BaggingFreq > 0 && round%b.BaggingFreq == 0 && !b.GOSS {
+			bags[j] = sampleRows(N, b.BaggingFraction, rngs[j])
+		}
+
+		// DART computes this round's gradients without the dropped trees
+		var dropped []int
+		if dart {
+			dropped = b.dropRounds(j, dropRngs[j])
+			b.addRoundScores(j, dropped, preds[j], X, -1)
+		}
+
+		// Compute gradients and hessians for target j
+		K := b.numModels(j)
+		grads := make([][]float64, K)
+		hesss := make([][]float64, K)
+		for k := range K {
+			grads[k] = make([]float64, N)
+			hesss[k] = make([]float64, N)
+		}
+		// A random forest fits every tree to the gradients at the
+		// init scores
+		scores := preds[j]
+		if rf {
+			scores = base[j]
+		}
+		cfg.gradients(b.objective(j), labels[j], scores, grads, hesss)
+		if cfg.weights != nil {
+			for k := range K {
+				for i, w := range cfg.weights {
+					grads[k][i] *= w
+					hesss[k][i] *= w
+				}
 			}
+		}
+		bag := bags[j]
+		if b.GOSS && float64(offsets[j]+round) >= 1/b.LearningRate {
+			bag = gossSample(grads, hesss, b.TopRate, b.OtherRate, rngs[j])
+		}
 
-			// Build one histogram‐based tree on (X, grad, hess)
-			treeJ := tree.BuildHistogramTree(
-				X,
-				grad,
-				hess,
-				0,            
-				b.MaxDepth,   
-				b.MinSamples, 
-				b.NumBins,    
-			)
+		prevRounds := len(b.Trees[j]) / K
+		for k := range K {
+			// Build one histogram‐based tree on (ds, grad, hess)
+			var treeJ *tree.Node
+			if b.LeafWise {
+				treeJ = tree.BuildLeafWiseTree(ds, bag, grads[k], hesss[k], params)
+			} else {
+				treeJ = tree.BuildHistogramTree(ds, bag, grads[k], hesss[k], params)
+			}
+			if dart {
+				tree.ScaleLeaves(treeJ, 1/float64(len(dropped)+1))
+			}
 			b.Trees[j] = append(b.Trees[j], treeJ)
 
-			// Update preds[i][j] += learningRate * tree prediction
-			for i := range N {
-				val := tree.PredictTree(treeJ, X[i])
-				preds[i][j] += b.LearningRate * val
+			// Update preds[j][k][i] += learningRate * tree prediction,
+			// or fold the tree into the forest's mean
+			if rf {
+				averageTreeScores(preds[j][k], base[j][k], treeJ, X, prevRounds)
+				for _, vs := range cfg.valid {
+					averageTreeScores(vs.scores[j][k], vs.base[j][k], treeJ, vs.X, prevRounds)
+				}
+				continue
+			}
+			b.addTreeScores(preds[j][k], treeJ, X)
+			for _, vs := range cfg.valid {
+				b.addTreeScores(vs.scores[j][k], treeJ, vs.X)
+			}
+		}
+		if len(dropped) > 0 {
+			b.normalizeDropped(j, dropped, preds[j], X, cfg.valid)
+		}
+
+		if len(cfg.valid) == 0 {
+			return
+		}
+		values := b.evalTarget(j, cfg.valid, metrics[j])
+		if best := stoppers[j].update(values, offsets[j]+round+1, b.EarlyStoppingRounds); best > 0 {
+			b.BestIteration[j] = best
+			stopped[j] = true
+		}
+	}
+	for round := 0; round < nRounds; round++ {
+		forEachTarget(T, targetThreads, func(j int) {
+			if !stopped[j] {
+				boostTarget(j, round)
+			}
+		})
+	}
+
+	// Without an early stop, the best round of the first metric on the
+	// first validation set is still the one Predict should use
+	if b.EarlyStoppingRounds > 0 && len(cfg.valid) > 0 {
+		for j := range T {
+			if !stopped[j] {
+				b.BestIteration[j] = stoppers[j].bestRound[0]
 			}
 		}
 	}
+	return nil
 }
 
-// Predict returns a slice of length T (numTargets), giving the boosted ensemble
-// output for a single feature vector x.
-func (b *Booster) Predict(x []float64) []float64 {
-	out := make([]float64, b.NumTargets)
+// forEachTarget calls f(j) for every target j < T on up to threads
+// goroutines and waits for all calls to return.
+func forEachTarget(T, threads int, f func(j int)) {
+	if threads <= 1 {
+		for j := range T {
+			f(j)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	next := make(chan int, T)
+	for j := range T {
+		next <- j
+	}
+	close(next)
+	for range min(threads, T) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range next {
+				f(j)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// treeParams collects the tree-growing settings of the booster.
+func (b *Booster) treeParams() tree.Params {
+	return tree.Params{
+		MaxDepth:            b.MaxDepth,
+		MinSamples:          b.MinSamples,
+		NumLeaves:           b.NumLeaves,
+		LambdaL1:            b.LambdaL1,
+		LambdaL2:            b.LambdaL2,
+		MinGainToSplit:      b.MinGainToSplit,
+		MinSumHessianInLeaf: b.MinSumHessianInLeaf,
+		MaxDeltaStep:        b.MaxDeltaStep,
+		MaxCatToOnehot:      b.MaxCatToOnehot,
+		MaxCatThreshold:     b.MaxCatThreshold,
+		CatSmooth:           b.CatSmooth,
+		CatL2:               b.CatL2,
+		MinDataPerGroup:     b.MinDataPerGroup,
+
+		MonotoneConstraints:    b.MonotoneConstraints,
+		InteractionConstraints: b.InteractionConstraints,
+
+		LinearTree:   b.LinearTree,
+		LinearLambda: b.LinearLambda,
+
+		FeatureFraction:       b.FeatureFraction,
+		FeatureFractionByNode: b.FeatureFractionByNode,
+		ExtraTrees:            b.ExtraTrees,
+	}
+}
+
+// sampleRows returns a sorted random subset holding fraction of the rows
+// 0..N-1 (at least one), or all of them when fraction ≤ 0 or ≥ 1.
+func sampleRows(N int, fraction float64, rng *rand.Rand) []int {
+	n := N
+	if fraction > 0 && fraction < 1 {
+		n = max(1, int(math.Round(fraction*float64(N))))
+	}
+	rows := rng.Perm(N)[:n]
+	sort.Ints(rows)
+	return rows
+}
+
+// objective returns the objective of target j, falling back to squared
+// error for targets without one.
+func (b *Booster) objective(j int) Objective {
+	if j < len(b.Objectives) && b.Objectives[j] != nil {
+		return b.Objectives[j]
+	}
+	obj, _ := newL2(ObjectiveParams{})
+	return obj
+}
+
+// numModels returns how many trees target j trains per round.
+func (b *Booster) numModels(j int) int {
+	return b.objective(j).NumModels()
+}
+
+// numIterations returns the number of rounds Predict uses for target j:
+// BestIteration[j] when set, otherwise every trained round.
+func (b *Booster) numIterations(j int) int {
+	total := len(b.Trees[j]) / b.numModels(j)
+	if j < len(b.BestIteration) && b.BestIteration[j] > 0 && b.BestIteration[j] < total {
+		return b.BestIteration[j]
+	}
+	return total
+}
+
+// PredictRaw returns, for each target, the untransformed ensemble scores for
+// a single feature vector x: one score per class for multiclass targets, a
+// single score otherwise. Only the first numIterations rounds are used, and
+// their outputs are summed with LearningRate, or averaged for "rf".
+func (b *Booster) PredictRaw(x []float64) [][]float64 {
+	out := make([][]float64, b.NumTargets)
 	for j := 0; j < b.NumTargets; j++ {
-		var sum float64
-		for _, tnode := range b.Trees[j] {
-			sum += b.LearningRate * tree.PredictTree(tnode, x)
+		K := b.numModels(j)
+		sums := make([]float64, K)
+		if j < len(b.InitScores) {
+			copy(sums, b.InitScores[j])
 		}
-		out[j] = sum
+		// Trees of a multiclass target are stored round by round, class by class
+		scale := b.treeScale(j)
+		for t, tnode := range b.Trees[j][:b.numIterations(j)*K] {
+			sums[t%K] += scale * tree.PredictTree(tnode, x)
+		}
+		out[j] = sums
+	}
+	return out
+}
+
+// Predict returns a slice of length T (numTargets) for a single feature
+// vector x, with each target's raw scores passed through its objective's
+// Transform: regression targets hold their single boosted output,
+// multiclass targets one probability per class.
+func (b *Booster) Predict(x []float64) [][]float64 {
+	out := b.PredictRaw(x)
+	for j := range out {
+		out[j] = b.objective(j).Transform(out[j])
 	}
 	return out
 }
@@ -0,0 +1,65 @@
+// booster/dart.go
+package booster
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// dropRounds picks the earlier rounds of target j that DART leaves out of
+// this round's gradients: none with probability SkipDrop, otherwise each
+// round with probability DropRate, keeping a random MaxDrop of them when
+// MaxDrop > 0.
+func (b *Booster) dropRounds(j int, rng *rand.Rand) []int {
+	numRounds := len(b.Trees[j]) / b.numModels(j)
+	if numRounds == 0 || rng.Float64() < b.SkipDrop {
+		return nil
+	}
+	var dropped []int
+	for r := range numRounds {
+		if rng.Float64() < b.DropRate {
+			dropped = append(dropped, r)
+		}
+	}
+	if b.MaxDrop > 0 && len(dropped) > b.MaxDrop {
+		rng.Shuffle(len(dropped), func(a, c int) { dropped[a], dropped[c] = dropped[c], dropped[a] })
+		dropped = dropped[:b.MaxDrop]
+		sort.Ints(dropped)
+	}
+	return dropped
+}
+
+// addRoundScores adds factor times the shrunken output of the trees of the
+// given rounds of target j on every row of X to scores[k].
+func (b *Booster) addRoundScores(j int, rounds []int, scores [][]float64, X [][]float64, factor float64) {
+	K := b.numModels(j)
+	for _, r := range rounds {
+		for k := range K {
+			t := b.Trees[j][r*K+k]
+			for i := range X {
+				scores[k][i] += factor * b.LearningRate * tree.PredictTree(t, X[i])
+			}
+		}
+	}
+}
+
+// normalizeDropped rescales the trees of the dropped rounds of target j by
+// n/(n+1), n being the number of dropped rounds, after the new trees were
+// scaled by 1/(n+1). The training scores, which the dropped trees were
+// removed from, get them back at their new weight; the validation scores
+// lose the difference.
+func (b *Booster) normalizeDropped(j int, dropped []int, preds [][]float64, X [][]float64, valid []*validSet) {
+	n := float64(len(dropped))
+	b.addRoundScores(j, dropped, preds, X, n/(n+1))
+	for _, vs := range valid {
+		b.addRoundScores(j, dropped, vs.scores[j], vs.X, -1/(n+1))
+	}
+	K := b.numModels(j)
+	for _, r := range dropped {
+		for k := range K {
+			tree.ScaleLeaves(b.Trees[j][r*K+k], n/(n+1))
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// booster/eval.go
+package booster
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// FitOption configures a single call to Fit.
+type FitOption func(*fitConfig)
+
+type fitConfig struct {
+	valid      []*validSet
+	weights    []float64
+	initScores map[int][][]float64
+
+	// querySizes are the query sizes given with WithQueries, which check
+	// turns into the bounds queries (see RankingObjective); validQueries
+	// holds the query sizes of validation sets by name.
+	queries      []int
+	querySizes   []int
+	validQueries map[string][]int
+}
+
+// WithValidSet adds a named held-out dataset (X is M×D, Y is M×T) whose
+// metrics are evaluated after every round and drive early stopping.
+func WithValidSet(name string, X, Y [][]float64) FitOption {
+	return func(c *fitConfig) {
+		c.valid = append(c.valid, &validSet{name: name, X: X, Y: Y})
+	}
+}
+
+// WithWeights weights the rows of X (weights has length N): the gradients
+// and hessians of row i, and its share of the base score computed with
+// BoostFromAverage, are multiplied by weights[i].
+func WithWeights(weights []float64) FitOption {
+	return func(c *fitConfig) {
+		c.weights = weights
+	}
+}
+
+// WithInitScore starts target j from per-row raw scores instead of its base
+// score: scores[i][k] is the starting score of model k for row i of X (N×K).
+// As in LightGBM they are not part of the model: InitScores[j] stays zero,
+// Predict does not add them back, and validation sets start from zero too.
+func WithInitScore(j int, scores [][]float64) FitOption {
+	return func(c *fitConfig) {
+		if c.initScores == nil {
+			c.initScores = make(map[int][][]float64)
+		}
+		c.initScores[j] = scores
+	}
+}
+
+// WithQueries groups the rows of X into queries for ranking objectives:
+// the first sizes[0] rows form query 0, the next sizes[1] rows query 1, and
+// so on, covering all N rows (LightGBM's group).
+func WithQueries(sizes []int) FitOption {
+	return func(c *fitConfig) {
+		c.querySizes = sizes
+	}
+}
+
+// WithValidQueries groups the rows of the validation set called name into
+// queries, as WithQueries does for X, so that ranking metrics are
+// evaluated query by query.
+func WithValidQueries(name string, sizes []int) FitOption {
+	return func(c *fitConfig) {
+		if c.validQueries == nil {
+			c.validQueries = make(map[string][]int)
+		}
+		c.validQueries[name] = sizes
+	}
+}
+
+// check verifies that the weights, init scores and query groups fit N rows
+// of b's targets, and resolves the query bounds.
+func (c *fitConfig) check(b *Booster, N int) error {
+	if c.weights != nil && len(c.weights) != N {
+		return fmt.Errorf("booster: %d weights for %d rows", len(c.weights), N)
+	}
+	if c.querySizes != nil {
+		if err := checkQueries(c.querySizes, N); err != nil {
+			return err
+		}
+		c.queries = queryBounds(c.querySizes)
+	}
+	for j := range b.NumTargets {
+		if _, ok := b.objective(j).(RankingObjective); ok && c.queries == nil {
+			return fmt.Errorf("booster: objective %q of target %d needs query groups, see WithQueries", b.objective(j).Name(), j)
+		}
+	}
+	for name, sizes := range c.validQueries {
+		i := slices.IndexFunc(c.valid, func(vs *validSet) bool { return vs.name == name })
+		if i < 0 {
+			return fmt.Errorf("booster: query groups for unknown validation set %q", name)
+		}
+		if err := checkQueries(sizes, len(c.valid[i].X)); err != nil {
+			return fmt.Errorf("booster: validation set %q: %w", name, err)
+		}
+		c.valid[i].queries = queryBounds(sizes)
+	}
+	for j, scores := range c.initScores {
+		if j < 0 || j >= b.NumTargets {
+			return fmt.Errorf("booster: init scores for target %d out of range [0, %d)", j, b.NumTargets)
+		}
+		if len(scores) != N {
+			return fmt.Errorf("booster: %d init scores for %d rows", len(scores), N)
+		}
+		for _, row := range scores {
+			if len(row) != b.numModels(j) {
+				return fmt.Errorf("booster: target %d needs %d init scores per row", j, b.numModels(j))
+			}
+		}
+	}
+	return nil
+}
+
+// validSet tracks the running raw scores of a validation dataset.
+type validSet struct {
+	name string
+	X, Y [][]float64
+	// queries are the query bounds given with WithValidQueries, or nil
+	queries []int
+
+	labels [][]float64   // labels[j][i]
+	scores [][][]float64 // scores[j][k][i], as for Fit's training scores
+	base   [][][]float64 // base[j][k][i], the scores before any tree
+}
+
+// gradients fills the gradients and hessians of obj at scores, passing the
+// query groups to ranking objectives.
+func (c *fitConfig) gradients(obj Objective, labels []float64, scores, grad, hess [][]float64) {
+	if ro, ok := obj.(RankingObjective); ok {
+		ro.QueryGradients(labels, scores, grad, hess, c.queries)
+		return
+	}
+	obj.Gradients(labels, scores, grad, hess)
+}
+
+// metricsFor resolves the metrics evaluated for target j: Booster.Metrics
+// if set, otherwise the default metric of the target's objective.
+func (b *Booster) metricsFor(j int) ([]Metric, error) {
+	names := b.Metrics
+	if len(names) == 0 {
+		name, ok := defaultMetrics[b.objective(j).Name()]
+		if !ok {
+			name = "l2"
+		}
+		names = []string{name}
+	}
+	out := make([]Metric, len(names))
+	for m, name := range names {
+		metric, err := NewMetric(name)
+		if err != nil {
+			return nil, err
+		}
+		out[m] = metric
+	}
+	return out, nil
+}
+
+// evalTarget evaluates every metric of target j on every validation set,
+// appends the values to EvalResults and returns them in (set, metric) order.
+func (b *Booster) evalTarget(j int, valid []*validSet, metrics []Metric) []float64 {
+	obj := b.objective(j)
+	K := obj.NumModels()
+	var values []float64
+	for _, vs := range valid {
+		M := len(vs.X)
+		preds := make([][]float64, M)
+		for i := range M {
+			// Transform may return raw itself, so every row needs its own
+			raw := make([]float64, K)
+			for k := range K {
+				raw[k] = vs.scores[j][k][i]
+			}
+			preds[i] = obj.Transform(raw)
+		}
+		if b.EvalResults[j][vs.name] == nil {
+			b.EvalResults[j][vs.name] = make(map[string][]float64)
+		}
+		for _, m := range metrics {
+			var v float64
+			if rm, ok := m.(RankingMetric); ok && vs.queries != nil {
+				v = rm.EvalQueries(vs.labels[j], preds, vs.queries)
+			} else {
+				v = m.Eval(vs.labels[j], preds)
+			}
+			b.EvalResults[j][vs.name][m.Name()] = append(b.EvalResults[j][vs.name][m.Name()], v)
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// earlyStopper remembers the best value and round of every (validation set,
+// metric) pair of one target.
+type earlyStopper struct {
+	higherBetter []bool
+	best         []float64
+	bestRound    []int
+}
+
+func newEarlyStopper(numSets int, metrics []Metric) *earlyStopper {
+	s := &earlyStopper{}
+	for range numSets {
+		for _, m := range metrics {
+			s.higherBetter = append(s.higherBetter, m.HigherIsBetter())
+		}
+	}
+	s.best = make([]float64, len(s.higherBetter))
+	s.bestRound = make([]int, len(s.higherBetter))
+	return s
+}
+
+// update records the values after `round` rounds (1-based) and returns the
+// best round of the first pair that has not improved for `patience` rounds,
+// or 0 if training should continue.
+func (s *earlyStopper) update(values []float64, round, patience int) int {
+	for p, v := range values {
+		improved := s.bestRound[p] == 0 ||
+			(s.higherBetter[p] && v > s.best[p]) ||
+			(!s.higherBetter[p] && v < s.best[p])
+		if improved {
+			s.best[p] = v
+			s.bestRound[p] = round
+		}
+	}
+	for p := range values {
+		if patience > 0 && round-s.bestRound[p] >= patience {
+			return s.bestRound[p]
+		}
+	}
+	return 0
+}
+
+// addTreeScores adds the shrunken output of t on every row of X to scores.
+func (b *Booster) addTreeScores(scores []float64, t *tree.Node, X [][]float64) {
+	for i := range X {
+		scores[i] += b.LearningRate * tree.PredictTree(t, X[i])
+	}
+}
+
+// addModelScores adds the outputs of the trees Predict uses for target j
+// on every row of X to scores[k], weighted as in PredictRaw.
+func (b *Booster) addModelScores(j int, scores [][]float64, X [][]float64) {
+	K := b.numModels(j)
+	scale := b.treeScale(j)
+	for t, tnode := range b.Trees[j][:b.numIterations(j)*K] {
+		for i := range X {
+			scores[t%K][i] += scale * tree.PredictTree(tnode, X[i])
+		}
+	}
+}
+
+// initScores returns K×N raw scores that all start at init[k].
+func initScores(init []float64, N int) [][]float64 {
+	scores := make([][]float64, len(init))
+	for k := range scores {
+		scores[k] = make([]float64, N)
+		for i := range N {
+			scores[k][i] = init[k]
+		}
+	}
+	return scores
+}
+
+// copyScores returns a copy of K×N scores.
+func copyScores(scores [][]float64) [][]float64 {
+	out := make([][]float64, len(scores))
+	for k := range scores {
+		out[k] = append([]float64(nil), scores[k]...)
+	}
+	return out
+}
+
+// column extracts column j of Y.
+func column(Y [][]float64, j int) []float64 {
+	col := make([]float64, len(Y))
+	for i := range Y {
+		col[i] = Y[i][j]
+	}
+	return col
+}
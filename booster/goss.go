@@ -0,0 +1,52 @@
+// booster/goss.go
+package booster
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// gossSample implements gradient-based one-side sampling: it keeps the
+// topRate share of the rows with the largest |g·h| (summed over models) and
+// a random otherRate share of the rest, whose gradients and hessians it
+// scales up in place by (1-topRate)/otherRate so the sums stay unbiased.
+// It returns the sampled rows in increasing order.
+func gossSample(grads, hesss [][]float64, topRate, otherRate float64, rng *rand.Rand) []int {
+	N := len(grads[0])
+	topN := int(math.Round(topRate * float64(N)))
+	otherN := int(math.Round(otherRate * float64(N)))
+	if topN+otherN >= N || otherN <= 0 {
+		rows := make([]int, N)
+		for i := range rows {
+			rows[i] = i
+		}
+		return rows
+	}
+
+	score := make([]float64, N)
+	for k := range grads {
+		for i := range score {
+			score[i] += math.Abs(grads[k][i] * hesss[k][i])
+		}
+	}
+	order := make([]int, N)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, c int) bool { return score[order[a]] > score[order[c]] })
+
+	rows := append([]int(nil), order[:topN]...)
+	rest := order[topN:]
+	amplify := float64(len(rest)) / float64(otherN)
+	for _, k := range rng.Perm(len(rest))[:otherN] {
+		i := rest[k]
+		for m := range grads {
+			grads[m][i] *= amplify
+			hesss[m][i] *= amplify
+		}
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+	return rows
+}
@@ -0,0 +1,61 @@
+// booster/importance.go
+package booster
+
+import (
+	"fmt"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// ImportanceType selects what FeatureImportance accumulates per feature.
+type ImportanceType int
+
+const (
+	// ImportanceSplit counts how many times a feature is split on.
+	ImportanceSplit ImportanceType = iota
+	// ImportanceGain sums the gain of the splits made on a feature.
+	ImportanceGain
+)
+
+// FeatureImportance returns, for each target, the split or gain importance
+// of every feature over the trees Predict uses, keyed by feature name (see
+// FeatureName). Features that are never split on are reported as 0.
+func (b *Booster) FeatureImportance(kind ImportanceType) []map[string]float64 {
+	out := make([]map[string]float64, b.NumTargets)
+	for j := range b.NumTargets {
+		imp := make([]float64, b.NumFeatures)
+		for _, t := range b.Trees[j][:b.numIterations(j)*b.numModels(j)] {
+			accumulateImportance(t, kind, imp)
+		}
+		out[j] = make(map[string]float64, len(imp))
+		for f, v := range imp {
+			out[j][b.FeatureName(f)] = v
+		}
+	}
+	return out
+}
+
+// FeatureName returns FeatureNames[f], or LightGBM's "Column_f" when the
+// booster has no name for feature f.
+func (b *Booster) FeatureName(f int) string {
+	if f < len(b.FeatureNames) && b.FeatureNames[f] != "" {
+		return b.FeatureNames[f]
+	}
+	return fmt.Sprintf("Column_%d", f)
+}
+
+func accumulateImportance(n *tree.Node, kind ImportanceType, imp []float64) {
+	if n.IsLeaf {
+		return
+	}
+	if n.FeatureIdx < len(imp) {
+		switch kind {
+		case ImportanceSplit:
+			imp[n.FeatureIdx]++
+		case ImportanceGain:
+			imp[n.FeatureIdx] += n.Gain
+		}
+	}
+	accumulateImportance(n.Left, kind, imp)
+	accumulateImportance(n.Right, kind, imp)
+}
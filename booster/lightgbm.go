@@ -0,0 +1,821 @@
+// booster/lightgbm.go
+package booster
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// SaveLightGBM writes one target of the booster in the plain-text model
+// format of the reference C++ LightGBM, so it can be loaded by the Python
+// and CLI tooling. Leaf values are written with the learning rate applied
+// and the init scores folded into the first round's trees, as LightGBM
+// does; only the first numIterations rounds are written. Random forests are
+// marked average_output and every tree carries the init score instead.
+func (b *Booster) SaveLightGBM(w io.Writer, target int) error {
+	if target < 0 || target >= b.NumTargets {
+		return fmt.Errorf("booster: save lightgbm: target %d out of range [0, %d)", target, b.NumTargets)
+	}
+	obj := b.objective(target)
+	objStr, err := lightgbmObjectiveString(obj)
+	if err != nil {
+		return err
+	}
+	K := obj.NumModels()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "tree")
+	fmt.Fprintln(bw, "version=v4")
+	fmt.Fprintf(bw, "num_class=%d\n", K)
+	fmt.Fprintf(bw, "num_tree_per_iteration=%d\n", K)
+	fmt.Fprintln(bw, "label_index=0")
+	fmt.Fprintf(bw, "max_feature_idx=%d\n", b.NumFeatures-1)
+	fmt.Fprintf(bw, "objective=%s\n", objStr)
+	if b.Boosting == "rf" {
+		fmt.Fprintln(bw, "average_output")
+	}
+	names := make([]string, b.NumFeatures)
+	infos := make([]string, b.NumFeatures)
+	for f := range names {
+		names[f] = strings.ReplaceAll(b.FeatureName(f), " ", "_")
+		infos[f] = "none"
+	}
+	fmt.Fprintf(bw, "feature_names=%s\n", strings.Join(names, " "))
+	fmt.Fprintf(bw, "feature_infos=%s\n", strings.Join(infos, " "))
+	fmt.Fprintln(bw)
+
+	// A random forest's trees are averaged, so each carries the init
+	// score and keeps its raw leaf values
+	rf := b.Boosting == "rf"
+	scale := b.LearningRate
+	if rf {
+		scale = 1
+	}
+	for t, node := range b.Trees[target][:b.numIterations(target)*K] {
+		var bias float64
+		if (t < K || rf) && target < len(b.InitScores) {
+			bias = b.InitScores[target][t%K]
+		}
+		ft := flattenTree(node, scale, bias)
+		ft.write(bw, t, scale)
+	}
+	fmt.Fprintln(bw, "end of trees")
+	fmt.Fprintln(bw)
+
+	// Split importance of the features that are used, most used first
+	fmt.Fprintln(bw, "feature_importances:")
+	splits := make([]float64, b.NumFeatures)
+	for _, t := range b.Trees[target][:b.numIterations(target)*K] {
+		accumulateImportance(t, ImportanceSplit, splits)
+	}
+	order := make([]int, 0, len(splits))
+	for f, v := range splits {
+		if v > 0 {
+			order = append(order, f)
+		}
+	}
+	sort.SliceStable(order, func(a, c int) bool { return splits[order[a]] > splits[order[c]] })
+	for _, f := range order {
+		fmt.Fprintf(bw, "%s=%d\n", names[f], int(splits[f]))
+	}
+	fmt.Fprintln(bw)
+
+	fmt.Fprintln(bw, "parameters:")
+	boosting := b.Boosting
+	if boosting == "" {
+		boosting = "gbdt"
+	}
+	fmt.Fprintf(bw, "[boosting: %s]\n", boosting)
+	fmt.Fprintf(bw, "[objective: %s]\n", obj.Name())
+	fmt.Fprintf(bw, "[learning_rate: %s]\n", formatFloat(b.LearningRate))
+	fmt.Fprintf(bw, "[num_leaves: %d]\n", b.NumLeaves)
+	fmt.Fprintf(bw, "[max_depth: %d]\n", b.MaxDepth)
+	fmt.Fprintf(bw, "[min_data_in_leaf: %d]\n", b.MinSamples)
+	fmt.Fprintf(bw, "[max_bin: %d]\n", b.NumBins)
+	fmt.Fprintf(bw, "[lambda_l1: %s]\n", formatFloat(b.LambdaL1))
+	fmt.Fprintf(bw, "[lambda_l2: %s]\n", formatFloat(b.LambdaL2))
+	fmt.Fprintf(bw, "[min_gain_to_split: %s]\n", formatFloat(b.MinGainToSplit))
+	fmt.Fprintf(bw, "[min_sum_hessian_in_leaf: %s]\n", formatFloat(b.MinSumHessianInLeaf))
+	fmt.Fprintf(bw, "[max_delta_step: %s]\n", formatFloat(b.MaxDeltaStep))
+	fmt.Fprintf(bw, "[categorical_feature: %s]\n", strings.ReplaceAll(joinInts(b.CategoricalFeatures), " ", ","))
+	fmt.Fprintf(bw, "[max_cat_to_onehot: %d]\n", b.MaxCatToOnehot)
+	fmt.Fprintf(bw, "[max_cat_threshold: %d]\n", b.MaxCatThreshold)
+	fmt.Fprintf(bw, "[cat_smooth: %s]\n", formatFloat(b.CatSmooth))
+	fmt.Fprintf(bw, "[cat_l2: %s]\n", formatFloat(b.CatL2))
+	fmt.Fprintf(bw, "[min_data_per_group: %d]\n", b.MinDataPerGroup)
+	fmt.Fprintf(bw, "[monotone_constraints: %s]\n", strings.ReplaceAll(joinInts(b.MonotoneConstraints), " ", ","))
+	groups := make([]string, len(b.InteractionConstraints))
+	for g, group := range b.InteractionConstraints {
+		groups[g] = "[" + strings.ReplaceAll(joinInts(group), " ", ",") + "]"
+	}
+	fmt.Fprintf(bw, "[interaction_constraints: %s]\n", strings.Join(groups, ","))
+	if b.LinearTree {
+		fmt.Fprintln(bw, "[linear_tree: 1]")
+	} else {
+		fmt.Fprintln(bw, "[linear_tree: 0]")
+	}
+	fmt.Fprintf(bw, "[linear_lambda: %s]\n", formatFloat(b.LinearLambda))
+	fmt.Fprintf(bw, "[bagging_fraction: %s]\n", formatFloat(b.BaggingFraction))
+	fmt.Fprintf(bw, "[bagging_freq: %d]\n", b.BaggingFreq)
+	fmt.Fprintf(bw, "[feature_fraction: %s]\n", formatFloat(b.FeatureFraction))
+	fmt.Fprintf(bw, "[feature_fraction_bynode: %s]\n", formatFloat(b.FeatureFractionByNode))
+	fmt.Fprintf(bw, "[seed: %d]\n", b.Seed)
+	if b.ExtraTrees {
+		fmt.Fprintln(bw, "[extra_trees: 1]")
+	} else {
+		fmt.Fprintln(bw, "[extra_trees: 0]")
+	}
+	fmt.Fprintf(bw, "[num_threads: %d]\n", b.NumThreads)
+	if b.GOSS {
+		fmt.Fprintln(bw, "[data_sample_strategy: goss]")
+	} else {
+		fmt.Fprintln(bw, "[data_sample_strategy: bagging]")
+	}
+	fmt.Fprintf(bw, "[top_rate: %s]\n", formatFloat(b.TopRate))
+	fmt.Fprintf(bw, "[other_rate: %s]\n", formatFloat(b.OtherRate))
+	fmt.Fprintf(bw, "[drop_rate: %s]\n", formatFloat(b.DropRate))
+	fmt.Fprintf(bw, "[max_drop: %d]\n", b.MaxDrop)
+	fmt.Fprintf(bw, "[skip_drop: %s]\n", formatFloat(b.SkipDrop))
+	fmt.Fprintf(bw, "[drop_seed: %d]\n", b.DropSeed)
+	fmt.Fprintln(bw, "end of parameters")
+	return bw.Flush()
+}
+
+// LoadLightGBM reads a model in the plain-text format of the reference C++
+// LightGBM and returns it as a single-target Booster. Leaf values in that
+// format already include shrinkage and init scores, so the returned booster
+// has a learning rate of 1 and zero init scores. Models marked
+// average_output load as "rf" boosters. The parameters section is not
+// read, so the booster is marked Imported: it predicts and explains like
+// the original, but Continue and Refit refuse it.
+func LoadLightGBM(r io.Reader) (*Booster, error) {
+	m, err := readLightGBM(r)
+	if err != nil {
+		return nil, err
+	}
+	b := NewBooster(1, 1.0, 0, 0, 0)
+	b.Trees[0] = m.trees
+	b.Objectives[0] = m.obj
+	b.InitScores = [][]float64{make([]float64, m.obj.NumModels())}
+	b.NumFeatures = m.numFeatures
+	b.Imported = true
+	if m.rf {
+		b.Boosting = "rf"
+	}
+	b.LinearTree = m.linear
+	if len(m.featureNames) == b.NumFeatures {
+		b.FeatureNames = m.featureNames
+	}
+	return b, nil
+}
+
+// LoadLightGBMTarget reads a LightGBM model like LoadLightGBM and installs
+// it as target j of b, replacing that target's trees and init score. The
+// model's objective must match b's objective for target j, its features
+// those of b, and its average_output flag b.Boosting == "rf". Because the
+// leaf values already include shrinkage, b.LearningRate must be 1; b is
+// marked Imported.
+func LoadLightGBMTarget(r io.Reader, b *Booster, j int) error {
+	if j < 0 || j >= b.NumTargets {
+		return fmt.Errorf("booster: load lightgbm: target %d out of range [0, %d)", j, b.NumTargets)
+	}
+	if b.LearningRate != 1 {
+		return fmt.Errorf("booster: load lightgbm: learning rate is %v, want 1", b.LearningRate)
+	}
+	m, err := readLightGBM(r)
+	if err != nil {
+		return err
+	}
+	obj := b.objective(j)
+	if m.obj.Name() != obj.Name() || m.obj.NumModels() != obj.NumModels() {
+		return fmt.Errorf("booster: load lightgbm: objective %q with %d models does not match target %d's %q with %d",
+			m.obj.Name(), m.obj.NumModels(), j, obj.Name(), obj.NumModels())
+	}
+	if b.NumFeatures != 0 && b.NumFeatures != m.numFeatures {
+		return fmt.Errorf("booster: load lightgbm: model has %d features, booster has %d", m.numFeatures, b.NumFeatures)
+	}
+	if m.rf != (b.Boosting == "rf") {
+		return fmt.Errorf("booster: load lightgbm: average_output does not match boosting %q", b.Boosting)
+	}
+
+	b.Trees[j] = m.trees
+	b.Objectives[j] = m.obj
+	for len(b.InitScores) < b.NumTargets {
+		b.InitScores = append(b.InitScores, nil)
+	}
+	b.InitScores[j] = make([]float64, m.obj.NumModels())
+	if j < len(b.BestIteration) {
+		b.BestIteration[j] = 0
+	}
+	b.NumFeatures = m.numFeatures
+	b.LinearTree = b.LinearTree || m.linear
+	if len(b.FeatureNames) == 0 && len(m.featureNames) == b.NumFeatures {
+		b.FeatureNames = m.featureNames
+	}
+	b.Imported = true
+	return nil
+}
+
+// lightGBMModel is the part of a LightGBM text model a Booster uses.
+type lightGBMModel struct {
+	obj          Objective
+	trees        []*tree.Node
+	numFeatures  int
+	featureNames []string
+	rf           bool
+	linear       bool
+}
+
+// readLightGBM parses a LightGBM text model.
+func readLightGBM(r io.Reader) (*lightGBMModel, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 1<<20), 1<<30)
+
+	header := map[string]string{}
+	var blocks []map[string]string
+	var cur map[string]string
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "end of trees" {
+			break
+		}
+		if line == "average_output" {
+			header[line] = ""
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if key == "Tree" {
+			cur = map[string]string{}
+			blocks = append(blocks, cur)
+			continue
+		}
+		if cur == nil {
+			header[key] = val
+		} else {
+			cur[key] = val
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("booster: load lightgbm: %w", err)
+	}
+
+	obj, err := parseLightGBMObjective(header["objective"])
+	if err != nil {
+		return nil, err
+	}
+	K := 1
+	if v, ok := header["num_tree_per_iteration"]; ok {
+		if K, err = strconv.Atoi(v); err != nil || K < 1 {
+			return nil, fmt.Errorf("booster: load lightgbm: bad num_tree_per_iteration %q", v)
+		}
+	}
+	if K != obj.NumModels() {
+		return nil, fmt.Errorf("booster: load lightgbm: %d trees per iteration for objective %q", K, obj.Name())
+	}
+	maxFeature, err := strconv.Atoi(header["max_feature_idx"])
+	if err != nil || maxFeature < 0 {
+		return nil, fmt.Errorf("booster: load lightgbm: bad max_feature_idx %q", header["max_feature_idx"])
+	}
+	if len(blocks)%K != 0 {
+		return nil, fmt.Errorf("booster: load lightgbm: %d trees is not a multiple of %d", len(blocks), K)
+	}
+
+	trees := make([]*tree.Node, len(blocks))
+	for t, kv := range blocks {
+		if trees[t], err = parseLightGBMTree(kv, maxFeature); err != nil {
+			return nil, fmt.Errorf("booster: load lightgbm: tree %d: %w", t, err)
+		}
+	}
+
+	m := &lightGBMModel{
+		obj:          obj,
+		trees:        trees,
+		numFeatures:  maxFeature + 1,
+		featureNames: strings.Fields(header["feature_names"]),
+	}
+	_, m.rf = header["average_output"]
+	for _, kv := range blocks {
+		if kv["is_linear"] == "1" {
+			m.linear = true
+		}
+	}
+	return m, nil
+}
+
+// Bits of a LightGBM decision_type: bit 0 marks a categorical split, bit 1
+// sends missing values left, and bits 2–3 hold the missing-value type.
+const (
+	categoricalMask = 1
+	defaultLeftMask = 2
+
+	missingTypeNone = 0
+	missingTypeZero = 1
+	missingTypeNaN  = 2
+)
+
+// flatTree is a tree in LightGBM's array layout: internal nodes and leaves
+// are numbered separately, and a child reference c < 0 means leaf ^c.
+type flatTree struct {
+	splitFeature   []int
+	splitGain      []float64
+	threshold      []float64
+	decisionType   []int
+	leftChild      []int
+	rightChild     []int
+	leafValue      []float64
+	leafWeight     []float64
+	leafCount      []int
+	internalWeight []float64
+	internalCount  []int
+
+	// The bitset of categorical split c (whose threshold is c) is
+	// catThreshold[catBoundaries[c]:catBoundaries[c+1]].
+	catBoundaries []int
+	catThreshold  []int
+
+	// In a linear tree leaf i outputs leafConst[i] plus leafCoeff[i] times
+	// the features leafFeatures[i], falling back to leafValue[i] when one
+	// of them is NaN.
+	linear       bool
+	leafConst    []float64
+	leafFeatures [][]int
+	leafCoeff    [][]float64
+}
+
+// flattenTree lays out root in LightGBM's array form, writing each leaf
+// value as scale*Value + bias, and scaling linear leaves likewise.
+func flattenTree(root *tree.Node, scale, bias float64) *flatTree {
+	ft := &flatTree{catBoundaries: []int{0}}
+	var add func(n *tree.Node) int
+	add = func(n *tree.Node) int {
+		if n.IsLeaf {
+			ft.leafValue = append(ft.leafValue, scale*n.Value+bias)
+			ft.leafWeight = append(ft.leafWeight, n.Cover)
+			ft.leafCount = append(ft.leafCount, n.Count)
+			leafConst := scale*n.Value + bias
+			coeffs := make([]float64, len(n.LinearCoeffs))
+			if n.LinearFeatures != nil {
+				ft.linear = true
+				leafConst = scale*n.LinearConst + bias
+				for k, c := range n.LinearCoeffs {
+					coeffs[k] = scale * c
+				}
+			}
+			ft.leafConst = append(ft.leafConst, leafConst)
+			ft.leafFeatures = append(ft.leafFeatures, n.LinearFeatures)
+			ft.leafCoeff = append(ft.leafCoeff, coeffs)
+			return ^(len(ft.leafValue) - 1)
+		}
+		idx := len(ft.splitFeature)
+		ft.splitFeature = append(ft.splitFeature, n.FeatureIdx)
+		ft.splitGain = append(ft.splitGain, n.Gain)
+		ft.internalWeight = append(ft.internalWeight, n.Cover)
+		ft.internalCount = append(ft.internalCount, n.Count)
+		decisionType := missingTypeNaN << 2
+		if n.Categories != nil {
+			ft.threshold = append(ft.threshold, float64(len(ft.catBoundaries)-1))
+			for _, word := range n.Categories {
+				ft.catThreshold = append(ft.catThreshold, int(word))
+			}
+			ft.catBoundaries = append(ft.catBoundaries, len(ft.catThreshold))
+			decisionType |= categoricalMask
+		} else {
+			ft.threshold = append(ft.threshold, n.Threshold)
+			if n.DefaultLeft {
+				decisionType |= defaultLeftMask
+			}
+		}
+		ft.decisionType = append(ft.decisionType, decisionType)
+		ft.leftChild = append(ft.leftChild, 0)
+		ft.rightChild = append(ft.rightChild, 0)
+		ft.leftChild[idx] = add(n.Left)
+		ft.rightChild[idx] = add(n.Right)
+		return idx
+	}
+	add(root)
+	return ft
+}
+
+// write emits the tree as the `Tree=t` block of a LightGBM model file.
+func (ft *flatTree) write(w io.Writer, t int, shrinkage float64) {
+	fmt.Fprintf(w, "Tree=%d\n", t)
+	fmt.Fprintf(w, "num_leaves=%d\n", len(ft.leafValue))
+	numCat := len(ft.catBoundaries) - 1
+	fmt.Fprintf(w, "num_cat=%d\n", numCat)
+	if len(ft.splitFeature) > 0 {
+		fmt.Fprintf(w, "split_feature=%s\n", joinInts(ft.splitFeature))
+		fmt.Fprintf(w, "split_gain=%s\n", joinFloats(ft.splitGain))
+		fmt.Fprintf(w, "threshold=%s\n", joinFloats(ft.threshold))
+		fmt.Fprintf(w, "decision_type=%s\n", joinInts(ft.decisionType))
+		fmt.Fprintf(w, "left_child=%s\n", joinInts(ft.leftChild))
+		fmt.Fprintf(w, "right_child=%s\n", joinInts(ft.rightChild))
+	}
+	fmt.Fprintf(w, "leaf_value=%s\n", joinFloats(ft.leafValue))
+	fmt.Fprintf(w, "leaf_weight=%s\n", joinFloats(ft.leafWeight))
+	fmt.Fprintf(w, "leaf_count=%s\n", joinInts(ft.leafCount))
+	if len(ft.splitFeature) > 0 {
+		fmt.Fprintf(w, "internal_weight=%s\n", joinFloats(ft.internalWeight))
+		fmt.Fprintf(w, "internal_count=%s\n", joinInts(ft.internalCount))
+	}
+	if numCat > 0 {
+		fmt.Fprintf(w, "cat_boundaries=%s\n", joinInts(ft.catBoundaries))
+		fmt.Fprintf(w, "cat_threshold=%s\n", joinInts(ft.catThreshold))
+	}
+	if ft.linear {
+		numFeatures := make([]int, len(ft.leafFeatures))
+		features := make([]string, len(ft.leafFeatures))
+		coeffs := make([]string, len(ft.leafCoeff))
+		for i := range ft.leafFeatures {
+			numFeatures[i] = len(ft.leafFeatures[i])
+			features[i] = joinInts(ft.leafFeatures[i])
+			coeffs[i] = joinFloats(ft.leafCoeff[i])
+		}
+		fmt.Fprintln(w, "is_linear=1")
+		fmt.Fprintf(w, "leaf_const=%s\n", joinFloats(ft.leafConst))
+		fmt.Fprintf(w, "num_features=%s\n", joinInts(numFeatures))
+		fmt.Fprintf(w, "leaf_features=%s\n", strings.Join(features, "  "))
+		fmt.Fprintf(w, "leaf_coeff=%s\n", strings.Join(coeffs, "  "))
+	} else {
+		fmt.Fprintln(w, "is_linear=0")
+	}
+	fmt.Fprintf(w, "shrinkage=%s\n", formatFloat(shrinkage))
+	fmt.Fprintln(w)
+}
+
+// parseLightGBMTree rebuilds a tree from the key=value lines of one
+// `Tree=` block of a model whose features are 0..maxFeature.
+func parseLightGBMTree(kv map[string]string, maxFeature int) (*tree.Node, error) {
+	numLeaves, err := strconv.Atoi(kv["num_leaves"])
+	if err != nil || numLeaves < 1 {
+		return nil, fmt.Errorf("bad num_leaves %q", kv["num_leaves"])
+	}
+	ft := &flatTree{}
+	if ft.leafValue, err = parseFloats(kv["leaf_value"]); err != nil {
+		return nil, fmt.Errorf("leaf_value: %w", err)
+	}
+	if len(ft.leafValue) != numLeaves {
+		return nil, fmt.Errorf("%d leaf values for %d leaves", len(ft.leafValue), numLeaves)
+	}
+	if ft.leafCount, err = parseInts(kv["leaf_count"]); err != nil {
+		return nil, fmt.Errorf("leaf_count: %w", err)
+	}
+	if ft.leafWeight, err = parseFloats(kv["leaf_weight"]); err != nil {
+		return nil, fmt.Errorf("leaf_weight: %w", err)
+	}
+	if ft.leafCount == nil {
+		ft.leafCount = make([]int, numLeaves)
+	}
+	if ft.leafWeight == nil {
+		ft.leafWeight = make([]float64, numLeaves)
+	}
+	if len(ft.leafCount) != numLeaves || len(ft.leafWeight) != numLeaves {
+		return nil, fmt.Errorf("leaf counts and weights must have %d entries", numLeaves)
+	}
+	if kv["is_linear"] == "1" {
+		if err := ft.parseLinear(kv, numLeaves, maxFeature); err != nil {
+			return nil, err
+		}
+	}
+	if numLeaves == 1 {
+		return ft.leaf(0), nil
+	}
+
+	numInternal := numLeaves - 1
+	if ft.splitFeature, err = parseInts(kv["split_feature"]); err != nil {
+		return nil, fmt.Errorf("split_feature: %w", err)
+	}
+	if ft.threshold, err = parseFloats(kv["threshold"]); err != nil {
+		return nil, fmt.Errorf("threshold: %w", err)
+	}
+	if ft.leftChild, err = parseInts(kv["left_child"]); err != nil {
+		return nil, fmt.Errorf("left_child: %w", err)
+	}
+	if ft.rightChild, err = parseInts(kv["right_child"]); err != nil {
+		return nil, fmt.Errorf("right_child: %w", err)
+	}
+	if ft.decisionType, err = parseInts(kv["decision_type"]); err != nil {
+		return nil, fmt.Errorf("decision_type: %w", err)
+	}
+	if ft.splitGain, err = parseFloats(kv["split_gain"]); err != nil {
+		return nil, fmt.Errorf("split_gain: %w", err)
+	}
+	if ft.internalCount, err = parseInts(kv["internal_count"]); err != nil {
+		return nil, fmt.Errorf("internal_count: %w", err)
+	}
+	if ft.internalWeight, err = parseFloats(kv["internal_weight"]); err != nil {
+		return nil, fmt.Errorf("internal_weight: %w", err)
+	}
+	// Optional arrays default to zeros
+	if ft.decisionType == nil {
+		ft.decisionType = make([]int, numInternal)
+	}
+	if ft.splitGain == nil {
+		ft.splitGain = make([]float64, numInternal)
+	}
+	if ft.internalCount == nil {
+		ft.internalCount = make([]int, numInternal)
+	}
+	if ft.internalWeight == nil {
+		ft.internalWeight = make([]float64, numInternal)
+	}
+	numCat := 0
+	if v, ok := kv["num_cat"]; ok {
+		if numCat, err = strconv.Atoi(v); err != nil || numCat < 0 {
+			return nil, fmt.Errorf("bad num_cat %q", v)
+		}
+	}
+	if numCat > 0 {
+		if ft.catBoundaries, err = parseInts(kv["cat_boundaries"]); err != nil {
+			return nil, fmt.Errorf("cat_boundaries: %w", err)
+		}
+		if ft.catThreshold, err = parseInts(kv["cat_threshold"]); err != nil {
+			return nil, fmt.Errorf("cat_threshold: %w", err)
+		}
+		if len(ft.catBoundaries) != numCat+1 {
+			return nil, fmt.Errorf("%d cat boundaries for %d categorical splits", len(ft.catBoundaries), numCat)
+		}
+		for c := 0; c < numCat; c++ {
+			if ft.catBoundaries[c] < 0 || ft.catBoundaries[c] > ft.catBoundaries[c+1] ||
+				ft.catBoundaries[c+1] > len(ft.catThreshold) {
+				return nil, fmt.Errorf("bad cat_boundaries")
+			}
+		}
+	}
+	for _, arr := range []int{
+		len(ft.splitFeature), len(ft.threshold), len(ft.leftChild), len(ft.rightChild),
+		len(ft.decisionType), len(ft.splitGain), len(ft.internalCount), len(ft.internalWeight),
+	} {
+		if arr != numInternal {
+			return nil, fmt.Errorf("split arrays must have %d entries", numInternal)
+		}
+	}
+	for _, f := range ft.splitFeature {
+		if f < 0 || f > maxFeature {
+			return nil, fmt.Errorf("split feature %d out of range [0, %d]", f, maxFeature)
+		}
+	}
+
+	var build func(ref, depth int) (*tree.Node, error)
+	build = func(ref, depth int) (*tree.Node, error) {
+		if depth > numLeaves {
+			return nil, fmt.Errorf("child references form a cycle")
+		}
+		if ref < 0 {
+			leaf := ^ref
+			if leaf >= numLeaves {
+				return nil, fmt.Errorf("leaf %d out of range", leaf)
+			}
+			return ft.leaf(leaf), nil
+		}
+		if ref >= numInternal {
+			return nil, fmt.Errorf("node %d out of range", ref)
+		}
+		decisionType := ft.decisionType[ref]
+		left, err := build(ft.leftChild[ref], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		right, err := build(ft.rightChild[ref], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node := &tree.Node{
+			FeatureIdx: ft.splitFeature[ref],
+			Left:       left,
+			Right:      right,
+			Gain:       ft.splitGain[ref],
+			Count:      ft.internalCount[ref],
+			Cover:      ft.internalWeight[ref],
+		}
+
+		// Categorical splits send the codes in their bitset left and
+		// everything else, NaN included, right
+		if decisionType&categoricalMask != 0 {
+			c := int(ft.threshold[ref])
+			if c < 0 || c >= numCat {
+				return nil, fmt.Errorf("categorical split %d out of range", c)
+			}
+			node.Categories = make([]uint32, 0, ft.catBoundaries[c+1]-ft.catBoundaries[c])
+			for _, word := range ft.catThreshold[ft.catBoundaries[c]:ft.catBoundaries[c+1]] {
+				node.Categories = append(node.Categories, uint32(word))
+			}
+			return node, nil
+		}
+
+		// Without a missing type LightGBM predicts NaN as 0
+		var defaultLeft bool
+		switch (decisionType >> 2) & 3 {
+		case missingTypeNone:
+			defaultLeft = 0 <= ft.threshold[ref]
+		case missingTypeNaN:
+			defaultLeft = decisionType&defaultLeftMask != 0
+		case missingTypeZero:
+			return nil, fmt.Errorf("zero-as-missing splits are not supported")
+		default:
+			return nil, fmt.Errorf("unknown missing type in decision_type %d", decisionType)
+		}
+		node.Threshold = ft.threshold[ref]
+		node.DefaultLeft = defaultLeft
+		return node, nil
+	}
+	return build(0, 0)
+}
+
+// parseLinear reads the linear models of the leaves of a linear tree. The
+// features and coefficients of all leaves are written one after the other,
+// so num_features says how many belong to each leaf. Features must lie in
+// [0, maxFeature].
+func (ft *flatTree) parseLinear(kv map[string]string, numLeaves, maxFeature int) error {
+	var err error
+	if ft.leafConst, err = parseFloats(kv["leaf_const"]); err != nil {
+		return fmt.Errorf("leaf_const: %w", err)
+	}
+	numFeatures, err := parseInts(kv["num_features"])
+	if err != nil {
+		return fmt.Errorf("num_features: %w", err)
+	}
+	features, err := parseInts(kv["leaf_features"])
+	if err != nil {
+		return fmt.Errorf("leaf_features: %w", err)
+	}
+	coeffs, err := parseFloats(kv["leaf_coeff"])
+	if err != nil {
+		return fmt.Errorf("leaf_coeff: %w", err)
+	}
+	if len(ft.leafConst) != numLeaves || len(numFeatures) != numLeaves {
+		return fmt.Errorf("linear leaf arrays must have %d entries", numLeaves)
+	}
+	for _, f := range features {
+		if f < 0 || f > maxFeature {
+			return fmt.Errorf("leaf feature %d out of range [0, %d]", f, maxFeature)
+		}
+	}
+	ft.linear = true
+	ft.leafFeatures = make([][]int, numLeaves)
+	ft.leafCoeff = make([][]float64, numLeaves)
+	for i, n := range numFeatures {
+		if n < 0 || n > len(features) || n > len(coeffs) {
+			return fmt.Errorf("leaf_features and leaf_coeff must hold %d entries for leaf %d", n, i)
+		}
+		ft.leafFeatures[i], features = features[:n], features[n:]
+		ft.leafCoeff[i], coeffs = coeffs[:n], coeffs[n:]
+	}
+	if len(features) != 0 || len(coeffs) != 0 {
+		return fmt.Errorf("leaf_features and leaf_coeff have entries beyond num_features")
+	}
+	return nil
+}
+
+// leaf returns leaf i of the flat tree as a Node.
+func (ft *flatTree) leaf(i int) *tree.Node {
+	n := &tree.Node{
+		IsLeaf: true,
+		Value:  ft.leafValue[i],
+		Count:  ft.leafCount[i],
+		Cover:  ft.leafWeight[i],
+	}
+	if ft.linear {
+		if len(ft.leafFeatures[i]) == 0 {
+			// LightGBM outputs leaf_const for leaves without features
+			n.Value = ft.leafConst[i]
+		} else {
+			n.LinearConst = ft.leafConst[i]
+			n.LinearFeatures = ft.leafFeatures[i]
+			n.LinearCoeffs = ft.leafCoeff[i]
+		}
+	}
+	return n
+}
+
+// lightgbmObjectiveString renders an objective the way LightGBM writes the
+// `objective=` line.
+func lightgbmObjectiveString(obj Objective) (string, error) {
+	var p ObjectiveParams
+	if po, ok := obj.(paramsObjective); ok {
+		p = po.Params()
+	}
+	switch obj.Name() {
+	case "regression", "regression_l1", "cross_entropy":
+		return obj.Name(), nil
+	case "huber", "quantile":
+		alpha := p.Alpha
+		if alpha == 0 {
+			alpha = 0.9
+		}
+		return fmt.Sprintf("%s alpha:%s", obj.Name(), formatFloat(alpha)), nil
+	case "poisson":
+		return "poisson", nil
+	case "tweedie":
+		rho := p.TweedieVariancePower
+		if rho == 0 {
+			rho = 1.5
+		}
+		return fmt.Sprintf("tweedie tweedie_variance_power:%s", formatFloat(rho)), nil
+	case "binary":
+		return "binary sigmoid:1", nil
+	case "multiclass":
+		return fmt.Sprintf("multiclass num_class:%d", p.NumClass), nil
+	case "lambdarank":
+		return "lambdarank", nil
+	}
+	return "", fmt.Errorf("booster: save lightgbm: objective %q has no LightGBM equivalent", obj.Name())
+}
+
+// parseLightGBMObjective builds the objective named by an `objective=` line,
+// e.g. "multiclass num_class:5" or "huber alpha:0.9".
+func parseLightGBMObjective(s string) (Objective, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("booster: load lightgbm: missing objective")
+	}
+	var p ObjectiveParams
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, ":")
+		var err error
+		switch key {
+		case "num_class":
+			p.NumClass, err = strconv.Atoi(val)
+		case "alpha":
+			p.Alpha, err = strconv.ParseFloat(val, 64)
+		case "tweedie_variance_power":
+			p.TweedieVariancePower, err = strconv.ParseFloat(val, 64)
+		case "sigmoid":
+			if v, perr := strconv.ParseFloat(val, 64); perr != nil || v != 1 {
+				err = fmt.Errorf("only sigmoid:1 is supported")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("booster: load lightgbm: objective %q: %v", s, err)
+		}
+	}
+	name := fields[0]
+	if name == "regression_l2" {
+		name = "regression"
+	}
+	obj, err := NewObjective(name, p)
+	if err != nil {
+		return nil, fmt.Errorf("booster: load lightgbm: %w", err)
+	}
+	return obj, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func joinInts(vals []int) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinFloats(vals []float64) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = formatFloat(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func parseInts(s string) ([]int, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	out := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	out := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
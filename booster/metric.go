@@ -0,0 +1,167 @@
+// booster/metric.go
+package booster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Metric scores predictions of one target on a labelled dataset. preds[i]
+// is the transformed prediction for row i, as returned by Predict for that
+// target (one value for regression, one probability per class otherwise).
+type Metric interface {
+	Name() string
+	// HigherIsBetter reports whether larger values mean a better model.
+	HigherIsBetter() bool
+	Eval(labels []float64, preds [][]float64) float64
+}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   = map[string]Metric{}
+)
+
+func init() {
+	for _, m := range []Metric{
+		&pointwiseMetric{name: "l1", loss: func(y, p float64) float64 { return math.Abs(p - y) }},
+		&pointwiseMetric{name: "l2", loss: func(y, p float64) float64 { return (p - y) * (p - y) }},
+		&pointwiseMetric{name: "rmse", loss: func(y, p float64) float64 { return (p - y) * (p - y) }, sqrt: true},
+		&pointwiseMetric{name: "poisson", loss: func(y, p float64) float64 {
+			return p - y*math.Log(max(p, 1e-15))
+		}},
+		&pointwiseMetric{name: "binary_logloss", loss: func(y, p float64) float64 {
+			if y > 0 {
+				return -math.Log(max(p, 1e-15))
+			}
+			return -math.Log(max(1-p, 1e-15))
+		}},
+		&pointwiseMetric{name: "binary_error", loss: func(y, p float64) float64 {
+			if (p > 0.5) != (y > 0) {
+				return 1
+			}
+			return 0
+		}},
+		&pointwiseMetric{name: "cross_entropy", loss: func(y, p float64) float64 {
+			p = min(max(p, 1e-15), 1-1e-15)
+			return -y*math.Log(p) - (1-y)*math.Log(1-p)
+		}},
+		&multiclassMetric{name: "multi_logloss", loss: func(prob []float64, label int) float64 {
+			return -math.Log(max(prob[label], 1e-15))
+		}},
+		&multiclassMetric{name: "multi_error", loss: func(prob []float64, label int) float64 {
+			for k, p := range prob {
+				if k != label && p > prob[label] {
+					return 1
+				}
+			}
+			return 0
+		}},
+		&ndcgMetric{k: 1},
+		&ndcgMetric{k: 3},
+		&ndcgMetric{k: 5},
+		&ndcgMetric{k: 10},
+	} {
+		metrics[m.Name()] = m
+	}
+}
+
+// defaultMetrics maps an objective name to the metric evaluated when
+// Booster.Metrics is empty.
+var defaultMetrics = map[string]string{
+	"regression":    "l2",
+	"regression_l1": "l1",
+	"huber":         "l2",
+	"quantile":      "l1",
+	"poisson":       "poisson",
+	"tweedie":       "l2",
+	"binary":        "binary_logloss",
+	"cross_entropy": "cross_entropy",
+	"multiclass":    "multi_logloss",
+	"lambdarank":    "ndcg@5",
+}
+
+// RegisterMetric makes a custom metric available under m.Name(), replacing
+// any metric already registered with that name.
+func RegisterMetric(m Metric) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics[m.Name()] = m
+}
+
+// NewMetric returns the metric registered under name.
+func NewMetric(name string) (Metric, error) {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	m, ok := metrics[name]
+	if !ok {
+		return nil, fmt.Errorf("booster: unknown metric %q", name)
+	}
+	return m, nil
+}
+
+// Metrics lists the names of all registered metrics.
+func Metrics() []string {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pointwiseMetric averages a per-row loss over single-output predictions,
+// optionally taking the square root of the mean.
+type pointwiseMetric struct {
+	name string
+	loss func(label, pred float64) float64
+	sqrt bool
+}
+
+func (m *pointwiseMetric) Name() string         { return m.name }
+func (m *pointwiseMetric) HigherIsBetter() bool { return false }
+
+func (m *pointwiseMetric) Eval(labels []float64, preds [][]float64) float64 {
+	if len(labels) == 0 {
+		return 0
+	}
+	var sum float64
+	for i, y := range labels {
+		sum += m.loss(y, preds[i][0])
+	}
+	avg := sum / float64(len(labels))
+	if m.sqrt {
+		return math.Sqrt(avg)
+	}
+	return avg
+}
+
+// multiclassMetric averages a per-row loss over class probabilities,
+// skipping rows whose label is not a known class index.
+type multiclassMetric struct {
+	name string
+	loss func(prob []float64, label int) float64
+}
+
+func (m *multiclassMetric) Name() string         { return m.name }
+func (m *multiclassMetric) HigherIsBetter() bool { return false }
+
+func (m *multiclassMetric) Eval(labels []float64, preds [][]float64) float64 {
+	var sum float64
+	var n int
+	for i, y := range labels {
+		label := int(y)
+		if label < 0 || label >= len(preds[i]) {
+			continue
+		}
+		sum += m.loss(preds[i], label)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
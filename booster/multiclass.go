@@ -0,0 +1,100 @@
+// booster/multiclass.go
+package booster
+
+import (
+	"fmt"
+	"math"
+)
+
+// multiclassObjective is the softmax log loss over NumClass classes. Labels
+// are class indices; negative (unknown) labels contribute nothing.
+type multiclassObjective struct {
+	numClass int
+}
+
+func newMulticlass(p ObjectiveParams) (Objective, error) {
+	if p.NumClass < 2 {
+		return nil, fmt.Errorf("booster: multiclass needs at least 2 classes, got %d", p.NumClass)
+	}
+	return &multiclassObjective{numClass: p.NumClass}, nil
+}
+
+func (o *multiclassObjective) Name() string   { return "multiclass" }
+func (o *multiclassObjective) NumModels() int { return o.numClass }
+
+func (o *multiclassObjective) Params() ObjectiveParams {
+	return ObjectiveParams{NumClass: o.numClass}
+}
+
+// InitScore starts every class at the log of its (weighted) prior.
+func (o *multiclassObjective) InitScore(labels, weights []float64) []float64 {
+	K := o.numClass
+	counts := make([]float64, K)
+	var total float64
+	for i, y := range labels {
+		if label := int(y); label >= 0 && label < K {
+			counts[label] += weight(weights, i)
+			total += weight(weights, i)
+		}
+	}
+	init := make([]float64, K)
+	if total == 0 {
+		return init
+	}
+	for k := range K {
+		init[k] = math.Log(max(counts[k]/total, 1e-15))
+	}
+	return init
+}
+
+func (o *multiclassObjective) Gradients(labels []float64, scores, grad, hess [][]float64) {
+	K := o.numClass
+	// Rescale the hessian as LightGBM does, so a K-class problem takes
+	// steps comparable to a binary one
+	factor := float64(K) / float64(K-1)
+	raw := make([]float64, K)
+	for i, y := range labels {
+		label := int(y)
+		if label < 0 || label >= K {
+			for k := range K {
+				grad[k][i], hess[k][i] = 0, 0
+			}
+			continue
+		}
+		for k := range K {
+			raw[k] = scores[k][i]
+		}
+		prob := softmax(raw)
+		for k := range K {
+			p := prob[k]
+			if k == label {
+				grad[k][i] = p - 1
+			} else {
+				grad[k][i] = p
+			}
+			hess[k][i] = factor * p * (1 - p)
+		}
+	}
+}
+
+func (o *multiclassObjective) Transform(raw []float64) []float64 {
+	return softmax(raw)
+}
+
+// softmax turns raw scores into probabilities that sum to 1.
+func softmax(raw []float64) []float64 {
+	maxRaw := math.Inf(-1)
+	for _, v := range raw {
+		maxRaw = max(maxRaw, v)
+	}
+	out := make([]float64, len(raw))
+	var sum float64
+	for k, v := range raw {
+		out[k] = math.Exp(v - maxRaw)
+		sum += out[k]
+	}
+	for k := range out {
+		out[k] /= sum
+	}
+	return out
+}
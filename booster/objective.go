@@ -0,0 +1,137 @@
+// booster/objective.go
+package booster
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Objective is the loss a target is boosted against. Scores are laid out
+// model-major: scores[k][i] is the raw score of model k (class k for
+// multiclass, 0 otherwise) for row i.
+type Objective interface {
+	// Name identifies the objective, e.g. "regression" or "multiclass".
+	Name() string
+	// NumModels is the number of trees trained per boosting round.
+	NumModels() int
+	// InitScore returns the constant raw score each model starts from.
+	// weights, when not nil, weights each label.
+	InitScore(labels, weights []float64) []float64
+	// Gradients fills grad[k][i] and hess[k][i] from labels[i] and scores[k][i].
+	Gradients(labels []float64, scores, grad, hess [][]float64)
+	// Transform maps the raw scores of one row to the output space
+	// (identity for regression, probabilities for classification).
+	Transform(raw []float64) []float64
+}
+
+// ObjectiveParams holds the tunables of the built-in objectives. Zero values
+// select the LightGBM defaults.
+type ObjectiveParams struct {
+	// NumClass is the number of classes of a multiclass objective.
+	NumClass int
+	// Alpha is the delta of the Huber loss and the level of the quantile loss.
+	Alpha float64
+	// TweedieVariancePower must lie in [1, 2).
+	TweedieVariancePower float64
+	// PoissonMaxDeltaStep safeguards the Poisson hessian.
+	PoissonMaxDeltaStep float64
+	// TruncationLevel is the number of top-ranked rows of each query whose
+	// pairs lambdarank scores; 0 selects 30.
+	TruncationLevel int
+}
+
+// paramsObjective is implemented by objectives that can report the
+// parameters they were built with, so that saved models can rebuild them
+// through NewObjective.
+type paramsObjective interface {
+	Params() ObjectiveParams
+}
+
+// ObjectiveFactory builds an objective from its parameters.
+type ObjectiveFactory func(p ObjectiveParams) (Objective, error)
+
+var (
+	objectivesMu sync.RWMutex
+	objectives   = map[string]ObjectiveFactory{
+		"regression":    newL2,
+		"regression_l1": newL1,
+		"huber":         newHuber,
+		"quantile":      newQuantile,
+		"poisson":       newPoisson,
+		"tweedie":       newTweedie,
+		"binary":        newBinary,
+		"cross_entropy": newCrossEntropy,
+		"multiclass":    newMulticlass,
+		"lambdarank":    newLambdarank,
+	}
+)
+
+// RegisterObjective makes a custom objective available to NewObjective under
+// name, replacing any objective already registered with that name.
+func RegisterObjective(name string, f ObjectiveFactory) {
+	objectivesMu.Lock()
+	defer objectivesMu.Unlock()
+	objectives[name] = f
+}
+
+// NewObjective builds the objective registered under name.
+func NewObjective(name string, p ObjectiveParams) (Objective, error) {
+	objectivesMu.RLock()
+	f, ok := objectives[name]
+	objectivesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("booster: unknown objective %q", name)
+	}
+	return f(p)
+}
+
+// Objectives lists the names of all registered objectives.
+func Objectives() []string {
+	objectivesMu.RLock()
+	defer objectivesMu.RUnlock()
+	names := make([]string, 0, len(objectives))
+	for name := range objectives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scalarObjective adapts a single-output loss to the Objective interface.
+type scalarObjective struct {
+	name      string
+	params    ObjectiveParams
+	init      func(labels, weights []float64) float64
+	gradient  func(label, score float64) (grad, hess float64)
+	transform func(raw float64) float64
+}
+
+func (o *scalarObjective) Name() string            { return o.name }
+func (o *scalarObjective) NumModels() int          { return 1 }
+func (o *scalarObjective) Params() ObjectiveParams { return o.params }
+
+func (o *scalarObjective) InitScore(labels, weights []float64) []float64 {
+	if len(labels) == 0 {
+		return []float64{0}
+	}
+	return []float64{o.init(labels, weights)}
+}
+
+func (o *scalarObjective) Gradients(labels []float64, scores, grad, hess [][]float64) {
+	for i, y := range labels {
+		grad[0][i], hess[0][i] = o.gradient(y, scores[0][i])
+	}
+}
+
+func (o *scalarObjective) Transform(raw []float64) []float64 {
+	out := make([]float64, len(raw))
+	for k, v := range raw {
+		if o.transform != nil {
+			out[k] = o.transform(v)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,214 @@
+// booster/rank.go
+package booster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RankingObjective is implemented by objectives whose gradients depend on
+// which rows belong to the same query, such as lambdarank. Fit and Refit
+// call QueryGradients instead of Gradients for them, with the query groups
+// given by WithQueries.
+type RankingObjective interface {
+	Objective
+	// QueryGradients is Gradients for rows grouped into queries: query q
+	// holds rows [bounds[q], bounds[q+1]).
+	QueryGradients(labels []float64, scores, grad, hess [][]float64, bounds []int)
+}
+
+// RankingMetric is implemented by metrics evaluated query by query.
+// Validation sets given query groups with WithValidQueries are scored with
+// EvalQueries; Eval treats all rows as one query.
+type RankingMetric interface {
+	Metric
+	EvalQueries(labels []float64, preds [][]float64, bounds []int) float64
+}
+
+// queryBounds turns query sizes into the bounds of RankingObjective.
+func queryBounds(sizes []int) []int {
+	bounds := make([]int, len(sizes)+1)
+	for q, n := range sizes {
+		bounds[q+1] = bounds[q] + n
+	}
+	return bounds
+}
+
+// checkQueries verifies that the query sizes cover exactly N rows.
+func checkQueries(sizes []int, N int) error {
+	total := 0
+	for q, n := range sizes {
+		if n < 0 {
+			return fmt.Errorf("booster: query %d has negative size %d", q, n)
+		}
+		total += n
+	}
+	if total != N {
+		return fmt.Errorf("booster: query sizes sum to %d for %d rows", total, N)
+	}
+	return nil
+}
+
+// lambdarank is LightGBM's LambdaMART objective: pairwise logistic losses
+// within each query, weighted by the NDCG change of swapping the pair.
+// Labels are integer relevance grades with gain 2^label - 1.
+type lambdarank struct {
+	params     ObjectiveParams
+	truncation int
+}
+
+func newLambdarank(p ObjectiveParams) (Objective, error) {
+	truncation := p.TruncationLevel
+	if truncation == 0 {
+		truncation = 30
+	}
+	if truncation < 0 {
+		return nil, fmt.Errorf("booster: lambdarank truncation level must be positive, got %d", truncation)
+	}
+	return &lambdarank{params: p, truncation: truncation}, nil
+}
+
+func (o *lambdarank) Name() string            { return "lambdarank" }
+func (o *lambdarank) NumModels() int          { return 1 }
+func (o *lambdarank) Params() ObjectiveParams { return o.params }
+
+// InitScore is 0: only score differences within a query matter.
+func (o *lambdarank) InitScore(labels, weights []float64) []float64 {
+	return []float64{0}
+}
+
+// Gradients treats all rows as a single query.
+func (o *lambdarank) Gradients(labels []float64, scores, grad, hess [][]float64) {
+	o.QueryGradients(labels, scores, grad, hess, []int{0, len(labels)})
+}
+
+func (o *lambdarank) QueryGradients(labels []float64, scores, grad, hess [][]float64, bounds []int) {
+	for q := 0; q+1 < len(bounds); q++ {
+		lo, hi := bounds[q], bounds[q+1]
+		o.queryGradients(labels[lo:hi], scores[0][lo:hi], grad[0][lo:hi], hess[0][lo:hi])
+	}
+}
+
+// queryGradients fills the gradients of the rows of one query, following
+// LightGBM's GetGradientsForOneQuery with lambdarank_norm on.
+func (o *lambdarank) queryGradients(labels, scores, grad, hess []float64) {
+	for i := range grad {
+		grad[i], hess[i] = 0, 0
+	}
+	invMaxDCG := maxDCG(labels, o.truncation)
+	if invMaxDCG == 0 {
+		return
+	}
+	invMaxDCG = 1 / invMaxDCG
+
+	// Rows by decreasing score
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	bestScore := scores[order[0]]
+	worstScore := scores[order[len(order)-1]]
+
+	var sumLambdas float64
+	for i := 0; i < len(order)-1 && i < o.truncation; i++ {
+		for j := i + 1; j < len(order); j++ {
+			a, b := order[i], order[j]
+			if labels[a] == labels[b] {
+				continue
+			}
+			high, low := a, b
+			if labels[a] < labels[b] {
+				high, low = b, a
+			}
+			deltaScore := scores[high] - scores[low]
+			dcgGap := labelGain(labels[high]) - labelGain(labels[low])
+			pairedDiscount := math.Abs(discount(i) - discount(j))
+			deltaNDCG := dcgGap * pairedDiscount * invMaxDCG
+			if bestScore != worstScore {
+				deltaNDCG /= 0.01 + math.Abs(deltaScore)
+			}
+
+			lambda := 1 / (1 + math.Exp(deltaScore))
+			h := lambda * (1 - lambda) * deltaNDCG
+			lambda *= -deltaNDCG
+			grad[high] += lambda
+			hess[high] += h
+			grad[low] -= lambda
+			hess[low] += h
+			sumLambdas -= 2 * lambda
+		}
+	}
+	if sumLambdas > 0 {
+		factor := math.Log2(1+sumLambdas) / sumLambdas
+		for i := range grad {
+			grad[i] *= factor
+			hess[i] *= factor
+		}
+	}
+}
+
+func (o *lambdarank) Transform(raw []float64) []float64 {
+	return append([]float64(nil), raw...)
+}
+
+// labelGain is the DCG gain of a relevance label.
+func labelGain(label float64) float64 {
+	return math.Exp2(math.Trunc(max(label, 0))) - 1
+}
+
+// discount is the DCG discount of 0-based position i.
+func discount(i int) float64 {
+	return 1 / math.Log2(float64(i)+2)
+}
+
+// maxDCG is the DCG at k of the ideal ordering of labels.
+func maxDCG(labels []float64, k int) float64 {
+	sorted := append([]float64(nil), labels...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+	var dcg float64
+	for i := 0; i < len(sorted) && i < k; i++ {
+		dcg += labelGain(sorted[i]) * discount(i)
+	}
+	return dcg
+}
+
+// ndcgMetric is the NDCG at k averaged over queries; queries without
+// relevant rows count as 1, as in LightGBM.
+type ndcgMetric struct {
+	k int
+}
+
+func (m *ndcgMetric) Name() string         { return fmt.Sprintf("ndcg@%d", m.k) }
+func (m *ndcgMetric) HigherIsBetter() bool { return true }
+
+func (m *ndcgMetric) Eval(labels []float64, preds [][]float64) float64 {
+	return m.EvalQueries(labels, preds, []int{0, len(labels)})
+}
+
+func (m *ndcgMetric) EvalQueries(labels []float64, preds [][]float64, bounds []int) float64 {
+	if len(bounds) < 2 {
+		return 0
+	}
+	var sum float64
+	for q := 0; q+1 < len(bounds); q++ {
+		lo, hi := bounds[q], bounds[q+1]
+		best := maxDCG(labels[lo:hi], m.k)
+		if best == 0 {
+			sum++
+			continue
+		}
+		order := make([]int, hi-lo)
+		for i := range order {
+			order[i] = lo + i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return preds[order[a]][0] > preds[order[b]][0] })
+		var dcg float64
+		for i := 0; i < len(order) && i < m.k; i++ {
+			dcg += labelGain(labels[order[i]]) * discount(i)
+		}
+		sum += dcg / best
+	}
+	return sum / float64(len(bounds)-1)
+}
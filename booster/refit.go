@@ -0,0 +1,95 @@
+// booster/refit.go
+package booster
+
+import (
+	"fmt"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// PredictLeaf returns, for each target, the index of the leaf x falls into
+// in every tree Predict uses, in the order the trees are stored.
+func (b *Booster) PredictLeaf(x []float64) [][]int {
+	out := make([][]int, b.NumTargets)
+	for j := range b.NumTargets {
+		trees := b.Trees[j][:b.numIterations(j)*b.numModels(j)]
+		out[j] = make([]int, len(trees))
+		for t, tnode := range trees {
+			out[j][t] = tree.PredictLeaf(tnode, x)
+		}
+	}
+	return out
+}
+
+// Refit keeps the structure of every tree Predict uses but refits its leaf
+// values to (X, Y), replaying the rounds from the init scores as training
+// did: each leaf becomes decay*old + (1-decay)*new, where new is the
+// regularized output for the gradients of the rows of X that reach it
+// (LightGBM's refit_decay_rate; 0 discards the old values). WithWeights
+// and WithInitScore apply as in Fit; validation sets are ignored. Leaves
+// are clamped so that the trees still respect MonotoneConstraints. Models
+// loaded with LoadLightGBM cannot be refitted.
+func (b *Booster) Refit(X [][]float64, Y [][]float64, decay float64, opts ...FitOption) error {
+	if b.Imported {
+		return fmt.Errorf("booster: refit: cannot refit a model loaded from the LightGBM format")
+	}
+	var cfg fitConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	N := len(X)
+	if err := cfg.check(b, N); err != nil {
+		return err
+	}
+
+	params := b.treeParams()
+	for j := range b.NumTargets {
+		labels := column(Y, j)
+		K := b.numModels(j)
+		init := make([]float64, K)
+		if j < len(b.InitScores) {
+			copy(init, b.InitScores[j])
+		}
+		base := initScores(init, N)
+		if scores, ok := cfg.initScores[j]; ok {
+			for k := range K {
+				for i := range N {
+					base[k][i] += scores[i][k]
+				}
+			}
+		}
+		scores := copyScores(base)
+
+		grads := make([][]float64, K)
+		hesss := make([][]float64, K)
+		for k := range K {
+			grads[k] = make([]float64, N)
+			hesss[k] = make([]float64, N)
+		}
+		for round := range b.numIterations(j) {
+			if b.Boosting == "rf" {
+				cfg.gradients(b.objective(j), labels, base, grads, hesss)
+			} else {
+				cfg.gradients(b.objective(j), labels, scores, grads, hesss)
+			}
+			if cfg.weights != nil {
+				for k := range K {
+					for i, w := range cfg.weights {
+						grads[k][i] *= w
+						hesss[k][i] *= w
+					}
+				}
+			}
+			for k := range K {
+				t := b.Trees[j][round*K+k]
+				tree.RefitLeaves(t, X, grads[k], hesss[k], params, decay)
+				if b.Boosting == "rf" {
+					averageTreeScores(scores[k], base[k], t, X, round)
+				} else {
+					b.addTreeScores(scores[k], t, X)
+				}
+			}
+		}
+	}
+	return nil
+}
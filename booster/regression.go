@@ -0,0 +1,183 @@
+// booster/regression.go
+package booster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// newL2 is squared error: gradient = pred - target, hessian = 1.
+func newL2(p ObjectiveParams) (Objective, error) {
+	return &scalarObjective{
+		name:   "regression",
+		params: p,
+		init:   mean,
+		gradient: func(y, s float64) (float64, float64) {
+			return s - y, 1
+		},
+	}, nil
+}
+
+// newL1 is absolute error, started from the label median.
+func newL1(p ObjectiveParams) (Objective, error) {
+	return &scalarObjective{
+		name:   "regression_l1",
+		params: p,
+		init:   func(labels, weights []float64) float64 { return quantile(labels, weights, 0.5) },
+		gradient: func(y, s float64) (float64, float64) {
+			return sign(s - y), 1
+		},
+	}, nil
+}
+
+// newHuber is squared error for residuals within Alpha, absolute error beyond.
+func newHuber(p ObjectiveParams) (Objective, error) {
+	delta := p.Alpha
+	if delta == 0 {
+		delta = 0.9
+	}
+	if delta < 0 {
+		return nil, fmt.Errorf("booster: huber alpha must be positive, got %v", delta)
+	}
+	return &scalarObjective{
+		name:   "huber",
+		params: p,
+		init:   mean,
+		gradient: func(y, s float64) (float64, float64) {
+			diff := s - y
+			if math.Abs(diff) <= delta {
+				return diff, 1
+			}
+			return sign(diff) * delta, 1
+		},
+	}, nil
+}
+
+// newQuantile is the pinball loss for the Alpha quantile.
+func newQuantile(p ObjectiveParams) (Objective, error) {
+	alpha := p.Alpha
+	if alpha == 0 {
+		alpha = 0.9
+	}
+	if alpha <= 0 || alpha >= 1 {
+		return nil, fmt.Errorf("booster: quantile alpha must be in (0, 1), got %v", alpha)
+	}
+	return &scalarObjective{
+		name:   "quantile",
+		params: p,
+		init:   func(labels, weights []float64) float64 { return quantile(labels, weights, alpha) },
+		gradient: func(y, s float64) (float64, float64) {
+			if s >= y {
+				return 1 - alpha, 1
+			}
+			return -alpha, 1
+		},
+	}, nil
+}
+
+// newPoisson is the Poisson log-likelihood with a log link.
+func newPoisson(p ObjectiveParams) (Objective, error) {
+	maxDelta := p.PoissonMaxDeltaStep
+	if maxDelta == 0 {
+		maxDelta = 0.7
+	}
+	return &scalarObjective{
+		name:   "poisson",
+		params: p,
+		init:   logMean,
+		gradient: func(y, s float64) (float64, float64) {
+			return math.Exp(s) - y, math.Exp(s + maxDelta)
+		},
+		transform: math.Exp,
+	}, nil
+}
+
+// newTweedie is the Tweedie log-likelihood with a log link.
+func newTweedie(p ObjectiveParams) (Objective, error) {
+	rho := p.TweedieVariancePower
+	if rho == 0 {
+		rho = 1.5
+	}
+	if rho < 1 || rho >= 2 {
+		return nil, fmt.Errorf("booster: tweedie variance power must be in [1, 2), got %v", rho)
+	}
+	return &scalarObjective{
+		name:   "tweedie",
+		params: p,
+		init:   logMean,
+		gradient: func(y, s float64) (float64, float64) {
+			a := math.Exp((1 - rho) * s)
+			b := math.Exp((2 - rho) * s)
+			return -y*a + b, -y*(1-rho)*a + (2-rho)*b
+		},
+		transform: math.Exp,
+	}, nil
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// weight returns the weight of row i, 1 when there are no weights.
+func weight(weights []float64, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// mean returns the (weighted) mean of labels.
+func mean(labels, weights []float64) float64 {
+	var sum, total float64
+	for i, y := range labels {
+		w := weight(weights, i)
+		sum += w * y
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+func logMean(labels, weights []float64) float64 {
+	return math.Log(max(mean(labels, weights), 1e-15))
+}
+
+// quantile returns the alpha quantile of labels by linear interpolation, or
+// with weights the smallest label whose cumulative weight reaches alpha of
+// the total.
+func quantile(labels, weights []float64, alpha float64) float64 {
+	if weights != nil {
+		order := make([]int, len(labels))
+		var total float64
+		for i := range order {
+			order[i] = i
+			total += weights[i]
+		}
+		sort.Slice(order, func(a, c int) bool { return labels[order[a]] < labels[order[c]] })
+		var cum float64
+		for _, i := range order {
+			cum += weights[i]
+			if cum >= alpha*total {
+				return labels[i]
+			}
+		}
+		return labels[order[len(order)-1]]
+	}
+	sorted := make([]float64, len(labels))
+	copy(sorted, labels)
+	sort.Float64s(sorted)
+	pos := alpha * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	frac := pos - float64(lo)
+	return sorted[lo]*(1-frac) + sorted[hi]*frac
+}
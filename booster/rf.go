@@ -0,0 +1,45 @@
+// booster/rf.go
+package booster
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// forestRows draws the rows of one random-forest round: a BaggingFraction
+// share without replacement when BaggingFraction < 1, as LightGBM does,
+// and otherwise a bootstrap sample of N rows with replacement.
+func (b *Booster) forestRows(N int, rng *rand.Rand) []int {
+	if b.BaggingFraction > 0 && b.BaggingFraction < 1 {
+		return sampleRows(N, b.BaggingFraction, rng)
+	}
+	rows := make([]int, N)
+	for i := range rows {
+		rows[i] = rng.Intn(N)
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// averageTreeScores folds t into scores that hold init plus the mean output
+// of m earlier trees on every row of X, so they hold the mean over m+1.
+func averageTreeScores(scores, init []float64, t *tree.Node, X [][]float64, m int) {
+	for i := range X {
+		sum := (scores[i] - init[i]) * float64(m)
+		scores[i] = init[i] + (sum+tree.PredictTree(t, X[i]))/float64(m+1)
+	}
+}
+
+// treeScale returns the weight of each of the trees Predict uses for
+// target j: the learning rate when boosting, 1/numIterations for a
+// random forest, whose trees are averaged.
+func (b *Booster) treeScale(j int) float64 {
+	if b.Boosting == "rf" {
+		if n := b.numIterations(j); n > 0 {
+			return 1 / float64(n)
+		}
+	}
+	return b.LearningRate
+}
@@ -0,0 +1,78 @@
+// booster/save.go
+package booster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatVersion is the version of the JSON format written by Save. Load
+// accepts any version up to and including it.
+const FormatVersion = 1
+
+// objectiveSpec is how an objective is stored: by registered name and the
+// parameters it was built with.
+type objectiveSpec struct {
+	Name   string
+	Params ObjectiveParams
+}
+
+// savedBooster is the on-disk form of a Booster. Objectives shadow the
+// embedded interface slice with specs; EvalResults is training history
+// and is left out.
+type savedBooster struct {
+	Version int
+	*Booster
+	Objectives  []objectiveSpec
+	EvalResults json.RawMessage `json:",omitempty"`
+}
+
+// Save writes the booster (trees, init scores, objectives and every
+// training parameter) to w as versioned JSON.
+func (b *Booster) Save(w io.Writer) error {
+	saved := savedBooster{
+		Version:    FormatVersion,
+		Booster:    b,
+		Objectives: make([]objectiveSpec, b.NumTargets),
+	}
+	for j := range b.NumTargets {
+		obj := b.objective(j)
+		saved.Objectives[j].Name = obj.Name()
+		if po, ok := obj.(paramsObjective); ok {
+			saved.Objectives[j].Params = po.Params()
+		}
+	}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(saved); err != nil {
+		return fmt.Errorf("booster: save: %w", err)
+	}
+	return nil
+}
+
+// Load reads a booster written by Save. Custom objectives must be
+// registered with RegisterObjective before loading a model that uses them.
+func Load(r io.Reader) (*Booster, error) {
+	b := &Booster{}
+	saved := savedBooster{Booster: b}
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("booster: load: %w", err)
+	}
+	if saved.Version < 1 || saved.Version > FormatVersion {
+		return nil, fmt.Errorf("booster: load: unsupported format version %d", saved.Version)
+	}
+	if len(b.Trees) != b.NumTargets || len(saved.Objectives) != b.NumTargets {
+		return nil, fmt.Errorf("booster: load: model has %d targets but %d tree lists and %d objectives",
+			b.NumTargets, len(b.Trees), len(saved.Objectives))
+	}
+
+	b.Objectives = make([]Objective, b.NumTargets)
+	for j, spec := range saved.Objectives {
+		obj, err := NewObjective(spec.Name, spec.Params)
+		if err != nil {
+			return nil, fmt.Errorf("booster: load: target %d: %w", j, err)
+		}
+		b.Objectives[j] = obj
+	}
+	return b, nil
+}
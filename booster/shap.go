@@ -0,0 +1,36 @@
+// booster/shap.go
+package booster
+
+import (
+	"github.com/jesee-kuya/LightGBM/tree"
+)
+
+// PredictContrib explains PredictRaw(x) with exact TreeSHAP values over the
+// trees Predict uses. For each target it returns K×(D+1) values, where K is
+// the number of models of the target's objective (classes for multiclass,
+// 1 otherwise) and D = NumFeatures: for model k, entries [k*(D+1), k*(D+1)+D)
+// are the per-feature contributions and entry k*(D+1)+D is the bias (init
+// score plus the expected tree outputs). Each model's entries sum to its
+// raw score, except for linear trees, whose leaves are explained by their
+// constant values.
+func (b *Booster) PredictContrib(x []float64) [][]float64 {
+	D := b.NumFeatures
+	out := make([][]float64, b.NumTargets)
+	for j := range b.NumTargets {
+		K := b.numModels(j)
+		contrib := make([]float64, K*(D+1))
+		for k := range K {
+			if j < len(b.InitScores) {
+				contrib[k*(D+1)+D] = b.InitScores[j][k]
+			}
+		}
+		// Trees of a multiclass target are stored round by round, class by class
+		scale := b.treeScale(j)
+		for t, tnode := range b.Trees[j][:b.numIterations(j)*K] {
+			k := t % K
+			tree.TreeSHAP(tnode, x, contrib[k*(D+1):(k+1)*(D+1)], scale)
+		}
+		out[j] = contrib
+	}
+	return out
+}
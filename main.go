@@ -2,10 +2,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/jesee-kuya/LightGBM/artifact"
 	"github.com/jesee-kuya/LightGBM/booster"
 	"github.com/jesee-kuya/LightGBM/preprocess"
 	"github.com/jesee-kuya/LightGBM/reader"
@@ -14,7 +17,66 @@ import (
 	"github.com/jesee-kuya/LightGBM/writer"
 )
 
+// modelPath is where the trained booster and preprocessor are stored, so
+// restarts can serve without retraining. Delete it to force a retrain.
+const modelPath = "data/model.json"
+
 func main() {
+	// LOAD A SAVED MODEL, OR TRAIN AND SAVE ONE
+	boost, pre, err := artifact.LoadFile(modelPath)
+	switch {
+	case err == nil:
+		fmt.Printf("Loaded model from %s\n", modelPath)
+	case errors.Is(err, os.ErrNotExist):
+		boost, pre = train()
+		if err := artifact.SaveFile(modelPath, boost, pre); err != nil {
+			log.Fatalf("failed to save model to %s: %v", modelPath, err)
+		}
+		fmt.Printf("Model saved to %s\n", modelPath)
+	default:
+		log.Fatalf("failed to load model from %s: %v", modelPath, err)
+	}
+
+	// READ & MERGE TEST DATA 
+	cleanTest, err := reader.ReadCSV("data/test.csv")
+	if err != nil {
+		log.Fatalf("failed to read data/test.csv: %v", err)
+	}
+	rawTest, err := reader.ReadCSV("data/test_raw.csv")
+	if err != nil {
+		log.Fatalf("failed to read data/test_raw.csv: %v", err)
+	}
+	testRecords := util.MergeByID(cleanTest, rawTest)
+	if len(testRecords) == 0 {
+		log.Fatal("no test records after merging")
+	}
+	fmt.Printf("Merged test records: %d\n", len(testRecords))
+
+	// TRANSFORM TEST 
+	Xtest, _ := pre.Transform(testRecords)
+
+	// WRITE TEST PREDICTIONS 
+	outTest := "data/test_prediction.csv"
+	if err := writer.WritePredictions(testRecords, Xtest, boost, pre, outTest); err != nil {
+		log.Fatalf("failed to write test predictions: %v", err)
+	}
+	fmt.Printf("Test predictions written to %s\n", outTest)
+
+	fmt.Println("\nStarting prediction server on :8080...")
+
+	// Create the server instance with the trained model and preprocessor
+	predictionServer := server.NewServer(boost, pre)
+
+	// Set up the handler
+	http.HandleFunc("/predict", predictionServer.PredictHandler)
+
+	// Start the server
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
+
+// train reads and merges the training CSVs, fits the preprocessor and
+// booster on an 80/20 split and reports validation accuracy.
+func train() (*booster.Booster, *preprocess.Preprocessor) {
 	cleanTrain, err := reader.ReadCSV("data/train.csv")
 	if err != nil {
 		log.Fatalf("failed to read data/train.csv: %v", err)
@@ -55,45 +117,27 @@ func main() {
 	// TRAIN THE BOOSTER 
 	numTargets := len(YtrainAll[0]) 
 	boost := booster.NewBooster(numTargets, 0.1, 3, 5, 64)
-	boost.Fit(Xtrain, Ytrain, 50)
-	fmt.Println("Training complete.")
-
-	// EVALUATE ON VALIDATION 
-	util.Evaluate(boost, Xval, Yval, pre)
-
-	// READ & MERGE TEST DATA 
-	cleanTest, err := reader.ReadCSV("data/test.csv")
-	if err != nil {
-		log.Fatalf("failed to read data/test.csv: %v", err)
+	boost.FeatureNames = pre.FeatureNames()
+	boost.CategoricalFeatures = pre.CategoricalFeatures()
+	for j, numClasses := range pre.NumClasses() {
+		if numClasses < 2 {
+			continue
+		}
+		obj, err := booster.NewObjective("multiclass", booster.ObjectiveParams{NumClass: numClasses})
+		if err != nil {
+			log.Fatalf("failed to build objective for target %d: %v", j, err)
+		}
+		boost.Objectives[j] = obj
 	}
-	rawTest, err := reader.ReadCSV("data/test_raw.csv")
-	if err != nil {
-		log.Fatalf("failed to read data/test_raw.csv: %v", err)
-	}
-	testRecords := util.MergeByID(cleanTest, rawTest)
-	if len(testRecords) == 0 {
-		log.Fatal("no test records after merging")
-	}
-	fmt.Printf("Merged test records: %d\n", len(testRecords))
-
-	// TRANSFORM TEST 
-	Xtest, _ := pre.Transform(testRecords)
-
-	// WRITE TEST PREDICTIONS 
-	outTest := "data/test_prediction.csv"
-	if err := writer.WritePredictions(testRecords, Xtest, boost, pre, outTest); err != nil {
-		log.Fatalf("failed to write test predictions: %v", err)
+	boost.BoostFromAverage = true
+	boost.EarlyStoppingRounds = 10
+	if err := boost.Fit(Xtrain, Ytrain, 500, booster.WithValidSet("valid", Xval, Yval)); err != nil {
+		log.Fatalf("failed to train booster: %v", err)
 	}
-	fmt.Printf("Test predictions written to %s\n", outTest)
-
-	fmt.Println("\nStarting prediction server on :8080...")
+	fmt.Printf("Training complete. Best iterations: %v\n", boost.BestIteration)
 
-	// Create the server instance with the trained model and preprocessor
-	predictionServer := server.NewServer(boost, pre)
-
-	// Set up the handler
-	http.HandleFunc("/predict", predictionServer.PredictHandler)
+	// EVALUATE ON VALIDATION 
+	util.Evaluate(boost, Xval, Yval, pre)
 
-	// Start the server
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	return boost, pre
 }
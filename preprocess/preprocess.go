@@ -1,7 +1,9 @@
 package preprocess
 
 import (
+	"fmt"
 	"hash/fnv"
+	"math"
 	"strings"
 
 	"github.com/jesee-kuya/LightGBM/model"
@@ -114,7 +116,8 @@ func (p *Preprocessor) Fit(records []model.DataRecord) {
 }
 
 // Transform returns:
-//   - X: [][]float64  (numeric feature vectors, one row per record)
+//   - X: [][]float64  (numeric feature vectors, one row per record; categories
+//     not seen by Fit are encoded as NaN, i.e. missing)
 //   - Y: [][]float64  (each row is a slice of five encoded‐target ints, in float64 form)
 //
 // The order of targets in Y[i] is exactly:
@@ -134,7 +137,7 @@ func (p *Preprocessor) Transform(records []model.DataRecord) ([][]float64, [][]f
 		if idx, ok := p.countyEncoder[c]; ok {
 			featVec[0] = float64(idx)
 		} else {
-			featVec[0] = -1.0
+			featVec[0] = math.NaN()
 		}
 
 		// health level → float64(idx)
@@ -142,10 +145,10 @@ func (p *Preprocessor) Transform(records []model.DataRecord) ([][]float64, [][]f
 		if idx, ok := p.healthLevelEncoder[h]; ok {
 			featVec[1] = float64(idx)
 		} else {
-			featVec[1] = -1.0
+			featVec[1] = math.NaN()
 		}
 
-		// years of experience (already a float)
+		// years of experience (already a float, NaN if missing)
 		featVec[2] = r.YearsExperience
 
 		// competency → float64(idx)
@@ -153,7 +156,7 @@ func (p *Preprocessor) Transform(records []model.DataRecord) ([][]float64, [][]f
 		if idx, ok := p.competencyEncoder[comp]; ok {
 			featVec[3] = float64(idx)
 		} else {
-			featVec[3] = -1.0
+			featVec[3] = math.NaN()
 		}
 
 		// panel → float64(idx)
@@ -161,7 +164,7 @@ func (p *Preprocessor) Transform(records []model.DataRecord) ([][]float64, [][]f
 		if idx, ok := p.panelEncoder[pnl]; ok {
 			featVec[4] = float64(idx)
 		} else {
-			featVec[4] = -1.0
+			featVec[4] = math.NaN()
 		}
 
 		// bag‐of‐hashes on Prompt
@@ -227,6 +230,33 @@ func (p *Preprocessor) Transform(records []model.DataRecord) ([][]float64, [][]f
 	return X, Y
 }
 
+// FeatureNames returns a name for every column of X returned by Transform.
+func (p *Preprocessor) FeatureNames() []string {
+	names := []string{"county", "health_level", "years_experience", "competency", "panel"}
+	for j := 0; j < p.numPromptBuckets; j++ {
+		names = append(names, fmt.Sprintf("prompt_bucket_%d", j))
+	}
+	return names
+}
+
+// CategoricalFeatures returns the columns of X that Transform fills with
+// category codes: county, health level, competency and panel.
+func (p *Preprocessor) CategoricalFeatures() []int {
+	return []int{0, 1, 3, 4}
+}
+
+// NumClasses returns the number of distinct labels seen by Fit for each
+// target, in the same order as the columns of Y returned by Transform.
+func (p *Preprocessor) NumClasses() []int {
+	return []int{
+		len(p.clinicianEncoder),
+		len(p.gpt4Encoder),
+		len(p.llamaEncoder),
+		len(p.geminiEncoder),
+		len(p.ddxEncoder),
+	}
+}
+
 func (p *Preprocessor) ClinicianClasses() []string {
 	rev := make([]string, len(p.clinicianEncoder))
 	for str, idx := range p.clinicianEncoder {
@@ -0,0 +1,80 @@
+package preprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatVersion is the version of the JSON format written by Save.
+const FormatVersion = 1
+
+// savedPreprocessor is the on-disk form of a Preprocessor.
+type savedPreprocessor struct {
+	Version            int
+	NumPromptBuckets   int
+	CountyEncoder      map[string]int
+	HealthLevelEncoder map[string]int
+	CompetencyEncoder  map[string]int
+	PanelEncoder       map[string]int
+	ClinicianEncoder   map[string]int
+	GPT4Encoder        map[string]int
+	LLAMAEncoder       map[string]int
+	GEMINIEncoder      map[string]int
+	DDXEncoder         map[string]int
+}
+
+// Save writes every encoder and the prompt bucket count to w as versioned JSON.
+func (p *Preprocessor) Save(w io.Writer) error {
+	saved := savedPreprocessor{
+		Version:            FormatVersion,
+		NumPromptBuckets:   p.numPromptBuckets,
+		CountyEncoder:      p.countyEncoder,
+		HealthLevelEncoder: p.healthLevelEncoder,
+		CompetencyEncoder:  p.competencyEncoder,
+		PanelEncoder:       p.panelEncoder,
+		ClinicianEncoder:   p.clinicianEncoder,
+		GPT4Encoder:        p.gpt4Encoder,
+		LLAMAEncoder:       p.llamaEncoder,
+		GEMINIEncoder:      p.geminiEncoder,
+		DDXEncoder:         p.ddxEncoder,
+	}
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		return fmt.Errorf("preprocess: save: %w", err)
+	}
+	return nil
+}
+
+// Load reads a Preprocessor written by Save.
+func Load(r io.Reader) (*Preprocessor, error) {
+	var saved savedPreprocessor
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("preprocess: load: %w", err)
+	}
+	if saved.Version < 1 || saved.Version > FormatVersion {
+		return nil, fmt.Errorf("preprocess: load: unsupported format version %d", saved.Version)
+	}
+
+	return &Preprocessor{
+		countyEncoder:      orEmpty(saved.CountyEncoder),
+		healthLevelEncoder: orEmpty(saved.HealthLevelEncoder),
+		competencyEncoder:  orEmpty(saved.CompetencyEncoder),
+		panelEncoder:       orEmpty(saved.PanelEncoder),
+
+		numPromptBuckets: saved.NumPromptBuckets,
+
+		clinicianEncoder: orEmpty(saved.ClinicianEncoder),
+		gpt4Encoder:      orEmpty(saved.GPT4Encoder),
+		llamaEncoder:     orEmpty(saved.LLAMAEncoder),
+		geminiEncoder:    orEmpty(saved.GEMINIEncoder),
+		ddxEncoder:       orEmpty(saved.DDXEncoder),
+	}, nil
+}
+
+// orEmpty returns m, or an empty map if m is nil.
+func orEmpty(m map[string]int) map[string]int {
+	if m == nil {
+		return make(map[string]int)
+	}
+	return m
+}
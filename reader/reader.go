@@ -3,6 +3,7 @@ package reader
 import (
 	"encoding/csv"
 	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -44,7 +45,11 @@ func ReadCSV(path string) ([]model.DataRecord, error) {
 			}
 		}
 
-		years, _ := strconv.ParseFloat(rowMap["years of experience"], 64)
+		// Unparsable or empty years of experience are missing, not zero
+		years, err := strconv.ParseFloat(strings.TrimSpace(rowMap["years of experience"]), 64)
+		if err != nil {
+			years = math.NaN()
+		}
 
 		records = append(records, model.DataRecord{
 			ID:              rowMap["master_index"],
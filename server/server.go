@@ -3,6 +3,7 @@ package server
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
 
 	"github.com/jesee-kuya/LightGBM/booster"
@@ -67,10 +68,11 @@ func (s *Server) PredictHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the prompt is known; every other feature is left missing
 	record := model.DataRecord{
 		County:          "unknown",
 		HealthLevel:     "unknown",
-		YearsExperience: 5.0,
+		YearsExperience: math.NaN(),
 		Competency:      "unknown",
 		Panel:           "unknown",
 		Prompt:          req.IllnessDescription,
@@ -83,10 +85,10 @@ func (s *Server) PredictHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get prediction from the Booster (returns 5 predictions)
+	// Get prediction from the Booster (returns 5 per-target predictions)
 	rawPreds := s.Booster.Predict(X[0])
 	ddxLabels := s.Preproc.DDXClasses()
-	ddxIdx := util.Clamp(rawPreds[4], len(ddxLabels))
+	ddxIdx := util.ClassIndex(rawPreds[4], len(ddxLabels))
 	mainDiagnosis := ddxLabels[ddxIdx]
 
 	// Send JSON response with only the main diagnosis
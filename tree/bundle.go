@@ -0,0 +1,144 @@
+// tree/bundle.go
+package tree
+
+import (
+	"math"
+	"sort"
+)
+
+// maxBundleBins caps the bins of a bundle so it still fits a uint16 column.
+const maxBundleBins = math.MaxUint16 + 1
+
+// featureGroup is one stored column of a Dataset: either a single feature,
+// stored as is, or a bundle of mutually exclusive features. In a bundle,
+// bin 0 means every feature is at its default bin and bin offset[j]+b means
+// feature j is at bin b.
+type featureGroup struct {
+	features []int
+	numBins  int
+	col      binColumn
+}
+
+// bundleFeatures implements Exclusive Feature Bundling (Ke et al., 2017):
+// features are taken from the densest to the sparsest and each joins the
+// first bundle none of whose features leaves its default bin on the same
+// rows, so bundling loses no information. bins[j] holds the bin of every
+// row for feature j.
+func (ds *Dataset) bundleFeatures(bins [][]uint16) {
+	N, D := ds.NumRows, ds.NumFeatures
+
+	// Rows where each feature is away from its default bin
+	nonDefault := make([][]int, D)
+	for j := range D {
+		for i, bin := range bins[j] {
+			if int(bin) != ds.defaultBin[j] {
+				nonDefault[j] = append(nonDefault[j], i)
+			}
+		}
+	}
+	order := make([]int, D)
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, c int) bool {
+		return len(nonDefault[order[a]]) > len(nonDefault[order[c]])
+	})
+
+	type bundle struct {
+		features []int
+		numBins  int
+		used     []bool
+	}
+	var bundles []*bundle
+	for _, j := range order {
+		var home *bundle
+		for _, bd := range bundles {
+			if bd.numBins+ds.NumBins(j) > maxBundleBins {
+				continue
+			}
+			conflict := false
+			for _, i := range nonDefault[j] {
+				if bd.used[i] {
+					conflict = true
+					break
+				}
+			}
+			if !conflict {
+				home = bd
+				break
+			}
+		}
+		if home == nil {
+			home = &bundle{numBins: 1, used: make([]bool, N)}
+			bundles = append(bundles, home)
+		}
+		home.features = append(home.features, j)
+		home.numBins += ds.NumBins(j)
+		for _, i := range nonDefault[j] {
+			home.used[i] = true
+		}
+	}
+
+	ds.groups = make([]featureGroup, len(bundles))
+	ds.group = make([]int, D)
+	ds.offset = make([]int, D)
+	for g, bd := range bundles {
+		sort.Ints(bd.features)
+		group := featureGroup{features: bd.features}
+		if len(bd.features) == 1 {
+			j := bd.features[0]
+			group.numBins = ds.NumBins(j)
+			group.col = newBinColumn(N, group.numBins)
+			for i, bin := range bins[j] {
+				group.col.set(i, int(bin))
+			}
+		} else {
+			group.numBins = 1
+			for _, j := range bd.features {
+				ds.offset[j] = group.numBins
+				group.numBins += ds.NumBins(j)
+			}
+			group.col = newBinColumn(N, group.numBins)
+			for _, j := range bd.features {
+				for _, i := range nonDefault[j] {
+					group.col.set(i, ds.offset[j]+int(bins[j][i]))
+				}
+			}
+		}
+		for _, j := range bd.features {
+			ds.group[j] = g
+		}
+		ds.groups[g] = group
+	}
+}
+
+// unbundleHistogram splits the histogram of bundle g into one histogram
+// per feature, written to hists. The default bin of each feature, which
+// the bundle leaves implicit, gets whatever the other bins do not hold.
+func (ds *Dataset) unbundleHistogram(g int, gh []histBin, hists [][]histBin) {
+	group := &ds.groups[g]
+	if len(group.features) == 1 {
+		hists[group.features[0]] = gh
+		return
+	}
+	var total histBin
+	for _, h := range gh {
+		total.sumG += h.sumG
+		total.sumH += h.sumH
+		total.count += h.count
+	}
+	for _, j := range group.features {
+		hist := make([]histBin, ds.NumBins(j))
+		copy(hist, gh[ds.offset[j]:ds.offset[j]+len(hist)])
+		def := total
+		for b, h := range hist {
+			if b != ds.defaultBin[j] {
+				def.sumG -= h.sumG
+				def.sumH -= h.sumH
+				def.count -= h.count
+			}
+		}
+		hist[ds.defaultBin[j]] = def
+		hists[j] = hist
+	}
+}
@@ -0,0 +1,175 @@
+// tree/categorical.go
+package tree
+
+import (
+	"math"
+	"sort"
+)
+
+// findCategoricalSplit looks for a split of categorical feature j that
+// beats best, as LightGBM does: one-vs-rest for features with few
+// categories, otherwise a many-vs-many split along the categories sorted by
+// their gradient statistics. Missing values always go right, and monotone
+// constraints do not apply to categorical features. randThreshold ≥ 0
+// restricts the search to that category, or that number of sorted
+// categories, as drawn by randCategoricalThreshold.
+func (b *builder) findCategoricalSplit(
+	j int,
+	hist []histBin,
+	totalGrad, totalHess float64,
+	totalCount int,
+	parentGain float64,
+	c bounds,
+	randThreshold int,
+	best *split,
+) {
+	numCats := len(b.ds.BinUpperBounds[j])
+	p := &b.p
+
+	// valid reports whether both sides of a split respect the sample and
+	// hessian limits.
+	valid := func(H_L float64, C_L int) bool {
+		return C_L >= p.MinSamples && totalCount-C_L >= p.MinSamples &&
+			H_L >= p.MinSumHessianInLeaf && totalHess-H_L >= p.MinSumHessianInLeaf
+	}
+
+	if numCats <= p.MaxCatToOnehot {
+		for k := 0; k < numCats; k++ {
+			if randThreshold >= 0 && k != randThreshold {
+				continue
+			}
+			if !valid(hist[k].sumH, hist[k].count) {
+				continue
+			}
+			gain, leftOut, rightOut := p.splitGain(hist[k].sumG, hist[k].sumH,
+				totalGrad-hist[k].sumG, totalHess-hist[k].sumH, parentGain, c, 0)
+			if gain > p.MinGainToSplit && gain > best.gain {
+				catLeft := make([]bool, b.ds.NumBins(j))
+				catLeft[k] = true
+				*best = split{
+					feat: j, bin: -1, gain: gain, catLeft: catLeft,
+					leftOut: leftOut, rightOut: rightOut,
+				}
+			}
+		}
+		return
+	}
+
+	// Order the frequent categories by their smoothed mean gradient
+	var sorted []int
+	for k := 0; k < numCats; k++ {
+		if float64(hist[k].count) >= p.CatSmooth {
+			sorted = append(sorted, k)
+		}
+	}
+	ctr := func(k int) float64 { return hist[k].sumG / (hist[k].sumH + p.CatSmooth) }
+	sort.SliceStable(sorted, func(a, c int) bool { return ctr(sorted[a]) < ctr(sorted[c]) })
+
+	catParams := *p
+	catParams.LambdaL2 += p.CatL2
+	maxNumCats := min(p.MaxCatThreshold, (len(sorted)+1)/2)
+
+	// Grow the left side from the low end, then from the high end
+	for _, fromHigh := range []bool{false, true} {
+		var G_L, H_L float64
+		var C_L, groupCount int
+		for i := 0; i < len(sorted) && i < maxNumCats; i++ {
+			k := sorted[i]
+			if fromHigh {
+				k = sorted[len(sorted)-1-i]
+			}
+			G_L += hist[k].sumG
+			H_L += hist[k].sumH
+			C_L += hist[k].count
+			groupCount += hist[k].count
+
+			if C_L < p.MinSamples || H_L < p.MinSumHessianInLeaf {
+				continue
+			}
+			C_R := totalCount - C_L
+			if C_R < p.MinSamples || C_R < p.MinDataPerGroup || totalHess-H_L < p.MinSumHessianInLeaf {
+				break
+			}
+			if groupCount < p.MinDataPerGroup {
+				continue
+			}
+			groupCount = 0
+			if randThreshold >= 0 && i != randThreshold {
+				continue
+			}
+
+			gain, leftOut, rightOut := catParams.splitGain(G_L, H_L,
+				totalGrad-G_L, totalHess-H_L, parentGain, c, 0)
+			if gain > p.MinGainToSplit && gain > best.gain {
+				catLeft := make([]bool, b.ds.NumBins(j))
+				for n := 0; n <= i; n++ {
+					if fromHigh {
+						catLeft[sorted[len(sorted)-1-n]] = true
+					} else {
+						catLeft[sorted[n]] = true
+					}
+				}
+				*best = split{
+					feat: j, bin: -1, gain: gain, catLeft: catLeft,
+					leftOut: leftOut, rightOut: rightOut,
+				}
+			}
+		}
+	}
+}
+
+// randCategoricalThreshold draws the category a one-vs-rest split of
+// feature j tries, or the index of the last sorted category a
+// many-vs-many split sends left, for extra trees. It returns -1 when there
+// is nothing to draw from.
+func (b *builder) randCategoricalThreshold(j int, hist []histBin) int {
+	numCats := len(b.ds.BinUpperBounds[j])
+	n := numCats
+	if numCats > b.p.MaxCatToOnehot {
+		n = 0
+		for k := 0; k < numCats; k++ {
+			if float64(hist[k].count) >= b.p.CatSmooth {
+				n++
+			}
+		}
+		n = min(b.p.MaxCatThreshold, (n+1)/2)
+	}
+	if n <= 0 {
+		return -1
+	}
+	return b.p.Rand.Intn(n)
+}
+
+// categoryBitset returns the bitset of the category codes of feature j
+// whose bins are marked in catLeft.
+func (b *builder) categoryBitset(j int, catLeft []bool) []uint32 {
+	var bits []uint32
+	for k, left := range catLeft {
+		if !left || k >= len(b.ds.BinUpperBounds[j]) {
+			continue
+		}
+		c := int(b.ds.BinUpperBounds[j][k])
+		for len(bits) <= c/32 {
+			bits = append(bits, 0)
+		}
+		bits[c/32] |= 1 << (c % 32)
+	}
+	return bits
+}
+
+// inBitset reports whether category c is set in bits.
+func inBitset(bits []uint32, c int) bool {
+	return c >= 0 && c/32 < len(bits) && bits[c/32]&(1<<(c%32)) != 0
+}
+
+// categoricalChild routes x through a categorical split: codes in the
+// node's bitset go left, everything else, missing included, goes right.
+func (n *Node) categoricalChild(v float64) *Node {
+	if math.IsNaN(v) || v < 0 || v > math.MaxInt32 {
+		return n.Right
+	}
+	if inBitset(n.Categories, int(v)) {
+		return n.Left
+	}
+	return n.Right
+}
@@ -0,0 +1,281 @@
+// tree/dataset.go
+package tree
+
+import (
+	"math"
+	"sort"
+)
+
+// Dataset is a feature matrix that has been quantile-binned once up front.
+// Each feature keeps its own bin upper bounds, and every row stores only a
+// compact bin index per feature, so the tree builder never re-bins raw floats
+// and bin boundaries stay fixed for the whole ensemble. Mutually exclusive
+// sparse features are bundled into shared columns (see bundle.go).
+type Dataset struct {
+	NumRows     int
+	NumFeatures int
+
+	// BinUpperBounds[j][b] is the largest raw value of feature j that falls
+	// into bin b. The last bound of every numerical feature is +Inf; for
+	// categorical features each bin holds one category, whose code is its
+	// bound.
+	BinUpperBounds [][]float64
+
+	// hasMissing[j] marks features with NaN values in the training data;
+	// those get one extra bin, after the numeric ones, holding the NaNs.
+	hasMissing []bool
+
+	categorical []bool
+
+	// raw is the matrix the dataset was binned from, which linear leaves
+	// are fitted to.
+	raw [][]float64
+
+	// groups are the stored columns; feature j lives in groups[group[j]],
+	// where its bins are shifted by offset[j] if the group is a bundle.
+	// defaultBin[j] is the bin of 0, which a bundle leaves implicit.
+	groups     []featureGroup
+	group      []int
+	offset     []int
+	defaultBin []int
+}
+
+// binColumn stores the bin index of every row for one feature or bundle,
+// using uint8 when it has at most 256 bins and uint16 otherwise.
+type binColumn struct {
+	u8  []uint8
+	u16 []uint16
+}
+
+// newBinColumn allocates a column of n rows able to hold numBins bins.
+func newBinColumn(n, numBins int) binColumn {
+	if numBins <= math.MaxUint8+1 {
+		return binColumn{u8: make([]uint8, n)}
+	}
+	return binColumn{u16: make([]uint16, n)}
+}
+
+func (c *binColumn) set(i, bin int) {
+	if c.u8 != nil {
+		c.u8[i] = uint8(bin)
+	} else {
+		c.u16[i] = uint16(bin)
+	}
+}
+
+func (c *binColumn) at(i int) int {
+	if c.u8 != nil {
+		return int(c.u8[i])
+	}
+	return int(c.u16[i])
+}
+
+// NewDataset bins every column of X (N×D) into at most maxBins quantile bins.
+// NaN is treated as missing and gets a bin of its own. The columns listed in
+// categorical hold integer category codes instead; they get one bin per
+// distinct code, and NaN, negative codes and codes above math.MaxInt32 are
+// missing, as categoricalChild treats them at prediction time.
+func NewDataset(X [][]float64, maxBins int, categorical []int) *Dataset {
+	if maxBins < 2 {
+		maxBins = 2
+	}
+	if maxBins > math.MaxUint16+1 {
+		maxBins = math.MaxUint16 + 1
+	}
+
+	N := len(X)
+	D := 0
+	if N > 0 {
+		D = len(X[0])
+	}
+
+	ds := &Dataset{
+		NumRows:        N,
+		NumFeatures:    D,
+		BinUpperBounds: make([][]float64, D),
+		hasMissing:     make([]bool, D),
+		categorical:    make([]bool, D),
+		raw:            X,
+		defaultBin:     make([]int, D),
+	}
+	for _, j := range categorical {
+		if j >= 0 && j < D {
+			ds.categorical[j] = true
+		}
+	}
+
+	bins := make([][]uint16, D)
+	values := make([]float64, 0, N)
+	for j := 0; j < D; j++ {
+		values = values[:0]
+		for i := 0; i < N; i++ {
+			if v := X[i][j]; math.IsNaN(v) || (ds.categorical[j] && !validCode(v)) {
+				ds.hasMissing[j] = true
+			} else {
+				values = append(values, v)
+			}
+		}
+		if ds.categorical[j] {
+			codes := categoryCodes(values)
+			if len(codes) > math.MaxUint16 {
+				// Codes beyond the largest bin index count as missing
+				codes = codes[:math.MaxUint16]
+				ds.hasMissing[j] = true
+			}
+			ds.BinUpperBounds[j] = codes
+		} else {
+			numericBins := maxBins
+			if ds.hasMissing[j] {
+				numericBins--
+			}
+			ds.BinUpperBounds[j] = quantileBounds(values, max(numericBins, 1))
+		}
+
+		ds.defaultBin[j] = ds.BinOf(j, 0)
+
+		bins[j] = make([]uint16, N)
+		for i := 0; i < N; i++ {
+			bins[j][i] = uint16(ds.BinOf(j, X[i][j]))
+		}
+	}
+
+	ds.bundleFeatures(bins)
+	return ds
+}
+
+// NumBins returns the number of bins used by feature j, including its
+// missing-value bin if it has one.
+func (ds *Dataset) NumBins(j int) int {
+	if ds.hasMissing[j] {
+		return len(ds.BinUpperBounds[j]) + 1
+	}
+	return len(ds.BinUpperBounds[j])
+}
+
+// MissingBin returns the bin holding NaN values of feature j, or -1 if the
+// feature had no missing values.
+func (ds *Dataset) MissingBin(j int) int {
+	if ds.hasMissing[j] {
+		return len(ds.BinUpperBounds[j])
+	}
+	return -1
+}
+
+// IsCategorical reports whether feature j holds category codes.
+func (ds *Dataset) IsCategorical(j int) bool {
+	return ds.categorical[j]
+}
+
+// Bin returns the bin index of row i for feature j.
+func (ds *Dataset) Bin(i, j int) int {
+	g := &ds.groups[ds.group[j]]
+	bin := g.col.at(i)
+	if len(g.features) == 1 {
+		return bin
+	}
+	if off := ds.offset[j]; bin >= off && bin < off+ds.NumBins(j) {
+		return bin - off
+	}
+	return ds.defaultBin[j]
+}
+
+// BinOf maps a raw value of feature j to its bin index. NaN maps to the
+// missing-value bin, or is treated as 0 for features without one; so do
+// invalid (see validCode) and unknown codes of categorical features.
+func (ds *Dataset) BinOf(j int, v float64) int {
+	if ds.categorical[j] {
+		bounds := ds.BinUpperBounds[j]
+		if validCode(v) {
+			v = math.Trunc(v)
+			if b := sort.SearchFloat64s(bounds, v); b < len(bounds) && bounds[b] == v {
+				return b
+			}
+		}
+		if ds.hasMissing[j] {
+			return ds.MissingBin(j)
+		}
+		return 0
+	}
+	if math.IsNaN(v) {
+		if ds.hasMissing[j] {
+			return ds.MissingBin(j)
+		}
+		v = 0
+	}
+	return sort.SearchFloat64s(ds.BinUpperBounds[j], v)
+}
+
+// validCode reports whether v can be a category code: LightGBM limits
+// codes to non-negative int32 values.
+func validCode(v float64) bool {
+	return v >= 0 && v <= math.MaxInt32
+}
+
+// categoryCodes returns the distinct category codes among values, in
+// increasing order. Codes are truncated to integers.
+func categoryCodes(values []float64) []float64 {
+	seen := make(map[float64]bool)
+	var codes []float64
+	for _, v := range values {
+		c := math.Trunc(v)
+		if !seen[c] {
+			seen[c] = true
+			codes = append(codes, c)
+		}
+	}
+	sort.Float64s(codes)
+	return codes
+}
+
+// quantileBounds returns bin upper bounds so that every bin holds roughly the
+// same number of values. When there are no more distinct values than bins,
+// each distinct value gets its own bin. Bounds sit halfway between the last
+// value of a bin and the first value of the next one.
+func quantileBounds(values []float64, maxBins int) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	// Collapse to distinct values with their counts
+	var distinct []float64
+	var counts []int
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			distinct = append(distinct, v)
+			counts = append(counts, 1)
+		} else {
+			counts[len(counts)-1]++
+		}
+	}
+
+	if len(distinct) == 0 {
+		return []float64{math.Inf(1)}
+	}
+
+	var bounds []float64
+	if len(distinct) <= maxBins {
+		for k := 0; k < len(distinct)-1; k++ {
+			bounds = append(bounds, (distinct[k]+distinct[k+1])/2)
+		}
+	} else {
+		// Greedily close a bin once it has reached its share of the
+		// remaining values.
+		remaining := len(sorted)
+		binsLeft := maxBins
+		var inBin int
+		for k := 0; k < len(distinct)-1; k++ {
+			inBin += counts[k]
+			target := float64(remaining) / float64(binsLeft)
+			if float64(inBin) >= target {
+				bounds = append(bounds, (distinct[k]+distinct[k+1])/2)
+				remaining -= inBin
+				inBin = 0
+				binsLeft--
+				if binsLeft == 1 {
+					break
+				}
+			}
+		}
+	}
+	return append(bounds, math.Inf(1))
+}
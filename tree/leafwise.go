@@ -0,0 +1,91 @@
+// tree/leafwise.go
+package tree
+
+// leafCandidate is a current leaf of a tree being grown best-first, together
+// with the best split found for it.
+type leafCandidate struct {
+	node  *Node
+	rows  []int
+	hists [][]histBin
+	depth int
+	// bounds limits the outputs of the leaves grown from this one and used
+	// lists the features split on above it
+	bounds bounds
+	used   []int
+	best   split
+}
+
+// BuildLeafWiseTree fits a histogram-based regression tree to (ds, grad, hess)
+// by growing it best-first: at every step the leaf whose best split has the
+// highest gain is split, until the tree has p.NumLeaves leaves or no leaf has
+// a split beating p.MinGainToSplit. p.MaxDepth ≤ 0 leaves depth unlimited.
+// ds, rows, grad and hess are as for BuildHistogramTree.
+func BuildLeafWiseTree(
+	ds *Dataset,
+	rows []int,
+	grad []float64,
+	hess []float64,
+	p Params,
+) *Node {
+	if len(rows) == 0 {
+		return &Node{IsLeaf: true, Value: 0.0}
+	}
+
+	b := newBuilder(ds, grad, hess, p)
+	root := b.newLeafCandidate(rows, b.buildHistograms(rows), 0, unbounded, nil)
+	leaves := []*leafCandidate{root}
+
+	for len(leaves) < p.NumLeaves {
+		// Pick the leaf with the highest gain
+		best := -1
+		for k, l := range leaves {
+			if l.best.feat >= 0 && (best < 0 || l.best.gain > leaves[best].best.gain) {
+				best = k
+			}
+		}
+		if best < 0 {
+			break
+		}
+		l := leaves[best]
+
+		leftRows, rightRows := b.partitionRows(l.rows, l.best)
+		leftHists, rightHists := b.childHistograms(l.hists, leftRows, rightRows)
+		leftBounds, rightBounds := b.childBounds(l.best, l.bounds)
+		used := pathWith(l.used, l.best.feat)
+		left := b.newLeafCandidate(leftRows, leftHists, l.depth+1, leftBounds, used)
+		right := b.newLeafCandidate(rightRows, rightHists, l.depth+1, rightBounds, used)
+
+		// Turn the leaf into a split node in place
+		*l.node = *b.splitNode(l.best, len(l.rows), l.node.Cover)
+		l.node.Left = left.node
+		l.node.Right = right.node
+
+		leaves[best] = left
+		leaves = append(leaves, right)
+	}
+	for _, l := range leaves {
+		b.fitLinearLeaf(l.node, l.rows, l.used)
+	}
+	return root.node
+}
+
+// newLeafCandidate wraps a set of rows as a leaf with outputs bounded by c,
+// below splits on the features used, and, unless depth or sample limits
+// forbid it, finds its best split.
+func (b *builder) newLeafCandidate(rows []int, hists [][]histBin, depth int, c bounds, used []int) *leafCandidate {
+	sumGrad, sumHess := b.sums(rows)
+	l := &leafCandidate{
+		node:   b.leafNode(sumGrad, sumHess, len(rows), c),
+		rows:   rows,
+		hists:  hists,
+		depth:  depth,
+		bounds: c,
+		used:   used,
+		best:   split{feat: -1, bin: -1},
+	}
+	if (b.p.MaxDepth > 0 && depth >= b.p.MaxDepth) || len(rows) <= b.p.MinSamples {
+		return l
+	}
+	l.best = b.findBestSplit(hists, sumGrad, sumHess, len(rows), c, used)
+	return l
+}
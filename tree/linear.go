@@ -0,0 +1,144 @@
+// tree/linear.go
+package tree
+
+import (
+	"math"
+	"slices"
+)
+
+// output returns the output of leaf n for x: Value, or for a linear
+// leaf its linear model, unless one of the model's features is NaN in x.
+func (n *Node) output(x []float64) float64 {
+	if n.LinearFeatures == nil {
+		return n.Value
+	}
+	out := n.LinearConst
+	for k, j := range n.LinearFeatures {
+		v := x[j]
+		if math.IsNaN(v) {
+			return n.Value
+		}
+		out += n.LinearCoeffs[k] * v
+	}
+	return out
+}
+
+// fitLinearLeaf turns leaf into a linear leaf fitted to the given rows on
+// the numerical features among used, when p.LinearTree is set. The leaf
+// stays constant when there are no such features or too few rows.
+func (b *builder) fitLinearLeaf(leaf *Node, rows []int, used []int) {
+	if !b.p.LinearTree {
+		return
+	}
+	var features []int
+	for _, j := range used {
+		if !b.ds.IsCategorical(j) {
+			features = append(features, j)
+		}
+	}
+	if len(features) == 0 {
+		return
+	}
+	slices.Sort(features)
+	if c, coeffs, ok := fitLinear(b.ds.raw, rows, b.grad, b.hess, features, b.p.LinearLambda); ok {
+		leaf.LinearConst = c
+		leaf.LinearFeatures = features
+		leaf.LinearCoeffs = coeffs
+	}
+}
+
+// fitLinear fits c + Σ coeffs[k]*X[i][features[k]] to the gradients and
+// hessians of rows, the Newton step for a leaf with a linear output, with
+// an L2 penalty of lambda on the coefficients. Rows with a NaN feature are
+// skipped; ok is false when fewer than len(features)+1 rows remain or the
+// system is singular.
+func fitLinear(
+	X [][]float64,
+	rows []int,
+	grad, hess []float64,
+	features []int,
+	lambda float64,
+) (c float64, coeffs []float64, ok bool) {
+	// Solve (Σ h·x̃x̃ᵀ + Λ) β = -Σ g·x̃ for β = (coeffs, c), where x̃ is the
+	// row's features followed by a 1
+	m := len(features) + 1
+	a := make([][]float64, m)
+	for k := range a {
+		a[k] = make([]float64, m+1)
+	}
+	xt := make([]float64, m)
+	xt[m-1] = 1
+	n := 0
+rows:
+	for _, i := range rows {
+		for k, j := range features {
+			if math.IsNaN(X[i][j]) {
+				continue rows
+			}
+			xt[k] = X[i][j]
+		}
+		n++
+		for k := range m {
+			for l := k; l < m; l++ {
+				a[k][l] += hess[i] * xt[k] * xt[l]
+			}
+			a[k][m] -= grad[i] * xt[k]
+		}
+	}
+	if n < m {
+		return 0, nil, false
+	}
+	for k := range m {
+		for l := 0; l < k; l++ {
+			a[k][l] = a[l][k]
+		}
+		if k < m-1 {
+			a[k][k] += lambda
+		} else {
+			a[k][k] += kEpsilon
+		}
+	}
+
+	beta, ok := solve(a)
+	if !ok {
+		return 0, nil, false
+	}
+	return beta[m-1], beta[:m-1], true
+}
+
+// solve solves the linear system whose augmented matrix is a by Gaussian
+// elimination with partial pivoting, overwriting a. ok is false when the
+// system is (numerically) singular.
+func solve(a [][]float64) (x []float64, ok bool) {
+	m := len(a)
+	for k := range m {
+		p := k
+		for r := k + 1; r < m; r++ {
+			if math.Abs(a[r][k]) > math.Abs(a[p][k]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][k]) < 1e-12 {
+			return nil, false
+		}
+		a[k], a[p] = a[p], a[k]
+		for r := k + 1; r < m; r++ {
+			f := a[r][k] / a[k][k]
+			for l := k; l <= m; l++ {
+				a[r][l] -= f * a[k][l]
+			}
+		}
+	}
+	x = make([]float64, m)
+	for k := m - 1; k >= 0; k-- {
+		s := a[k][m]
+		for l := k + 1; l < m; l++ {
+			s -= a[k][l] * x[l]
+		}
+		x[k] = s / a[k][k]
+		if math.IsNaN(x[k]) || math.IsInf(x[k], 0) {
+			return nil, false
+		}
+	}
+	return x, true
+}
@@ -0,0 +1,25 @@
+// tree/parallel.go
+package tree
+
+import "sync"
+
+// parallelFor splits [0, n) into at most threads contiguous blocks and
+// calls f on each, concurrently, returning once all calls are done. With
+// threads ≤ 1 it simply calls f(0, n).
+func parallelFor(n, threads int, f func(lo, hi int)) {
+	if threads <= 1 || n <= 1 {
+		f(0, n)
+		return
+	}
+	threads = min(threads, n)
+	var wg sync.WaitGroup
+	for t := range threads {
+		lo, hi := t*n/threads, (t+1)*n/threads
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(lo, hi)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,171 @@
+// tree/params.go
+package tree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// kEpsilon keeps hessian sums strictly positive so leaf outputs stay finite
+// when LambdaL2 is 0.
+const kEpsilon = 1e-15
+
+// Params controls how a tree is grown and regularized.
+type Params struct {
+	// MaxDepth is the maximum depth allowed. Leaf-wise growth treats
+	// MaxDepth ≤ 0 as unlimited.
+	MaxDepth int
+	// MinSamples is the minimum number of samples needed to split a node
+	// and to keep in each child.
+	MinSamples int
+	// NumLeaves is the leaf budget of leaf-wise growth.
+	NumLeaves int
+
+	// LambdaL1 and LambdaL2 are the L1 and L2 penalties on leaf outputs.
+	LambdaL1 float64
+	LambdaL2 float64
+	// MinGainToSplit is the gain a split must exceed to be made.
+	MinGainToSplit float64
+	// MinSumHessianInLeaf is the hessian sum each child must reach.
+	MinSumHessianInLeaf float64
+	// MaxDeltaStep caps the absolute leaf output when > 0.
+	MaxDeltaStep float64
+
+	// Categorical features with at most MaxCatToOnehot categories are
+	// split one category against the rest. Otherwise categories seen in at
+	// least CatSmooth samples are sorted by G/(H+CatSmooth) and up to
+	// MaxCatThreshold of them, taken from either end, are sent left; CatL2
+	// is added to LambdaL2 when scoring such splits, and every group of
+	// categories added to a side must hold MinDataPerGroup samples.
+	MaxCatToOnehot  int
+	MaxCatThreshold int
+	CatSmooth       float64
+	CatL2           float64
+	MinDataPerGroup int
+
+	// FeatureFraction is the share of features a tree may split on, drawn
+	// once per tree; FeatureFractionByNode further samples that share of
+	// them for every split. Values ≤ 0 or ≥ 1 disable sampling. Rand drives
+	// both, as well as ExtraTrees, and must be set when any is in use.
+	FeatureFraction       float64
+	FeatureFractionByNode float64
+	Rand                  *rand.Rand
+
+	// ExtraTrees tries a single random threshold per feature at every
+	// split, drawn from Rand, instead of every bin boundary.
+	ExtraTrees bool
+
+	// NumThreads is the number of goroutines that build histograms and
+	// search for splits; ≤ 1 runs serially. Trees do not depend on it.
+	NumThreads int
+
+	// MonotoneConstraints[j] is +1 (-1) to make the tree's output
+	// non-decreasing (non-increasing) in numerical feature j, 0 for no
+	// constraint. Splits that break a constraint are rejected, and the
+	// children of a monotone split get output bounds meeting at the
+	// midpoint of their outputs.
+	MonotoneConstraints []int
+
+	// InteractionConstraints lists groups of features that may interact:
+	// every root-to-leaf path only splits on features of one group, so a
+	// node may use a feature if some group holds it together with all the
+	// features already split on above it. Features in no group are never
+	// used. Empty means no constraints.
+	InteractionConstraints [][]int
+
+	// LinearTree fits a linear model in every leaf, on the numerical
+	// features split on along its path, with an L2 penalty of LinearLambda
+	// on the coefficients. Monotone output bounds do not apply to it.
+	LinearTree   bool
+	LinearLambda float64
+}
+
+// bounds limits the outputs of the leaves below a node.
+type bounds struct {
+	min, max float64
+}
+
+var unbounded = bounds{math.Inf(-1), math.Inf(1)}
+
+// monotone returns the monotone constraint of feature j.
+func (p *Params) monotone(j int) int {
+	if j < len(p.MonotoneConstraints) {
+		return p.MonotoneConstraints[j]
+	}
+	return 0
+}
+
+// thresholdL1 shrinks a gradient sum towards zero by the L1 penalty.
+func thresholdL1(sumGrad, l1 float64) float64 {
+	reg := math.Max(0, math.Abs(sumGrad)-l1)
+	if sumGrad < 0 {
+		return -reg
+	}
+	return reg
+}
+
+// leafOutput is the regularized Newton step
+// -ThresholdL1(G, λ1) / (H + λ2), clipped to ±MaxDeltaStep.
+func (p *Params) leafOutput(sumGrad, sumHess float64) float64 {
+	out := -thresholdL1(sumGrad, p.LambdaL1) / (sumHess + kEpsilon + p.LambdaL2)
+	if p.MaxDeltaStep > 0 && math.Abs(out) > p.MaxDeltaStep {
+		out = math.Copysign(p.MaxDeltaStep, out)
+	}
+	return out
+}
+
+// boundedOutput is leafOutput clamped to c.
+func (p *Params) boundedOutput(sumGrad, sumHess float64, c bounds) float64 {
+	return min(max(p.leafOutput(sumGrad, sumHess), c.min), c.max)
+}
+
+// leafGain is the loss reduction of a leaf with the given sums when it
+// outputs leafOutput. Without L1 and MaxDeltaStep it is G^2/(H+λ2).
+func (p *Params) leafGain(sumGrad, sumHess float64) float64 {
+	return p.gainGivenOutput(sumGrad, sumHess, p.leafOutput(sumGrad, sumHess))
+}
+
+// gainGivenOutput is the loss reduction of a leaf with the given sums when
+// it outputs out.
+func (p *Params) gainGivenOutput(sumGrad, sumHess, out float64) float64 {
+	sg := thresholdL1(sumGrad, p.LambdaL1)
+	return -(2*sg*out + (sumHess+kEpsilon+p.LambdaL2)*out*out)
+}
+
+// splitGain returns the gain of splitting a node whose own output has gain
+// parentGain into children with the given sums, the children's outputs
+// bounded by c, and those outputs. mono is the monotone constraint of the
+// split feature; a split breaking it has gain -Inf.
+func (p *Params) splitGain(
+	leftGrad, leftHess, rightGrad, rightHess, parentGain float64,
+	c bounds,
+	mono int,
+) (gain, leftOut, rightOut float64) {
+	leftOut = p.boundedOutput(leftGrad, leftHess, c)
+	rightOut = p.boundedOutput(rightGrad, rightHess, c)
+	if (mono > 0 && leftOut > rightOut) || (mono < 0 && leftOut < rightOut) {
+		return math.Inf(-1), leftOut, rightOut
+	}
+	// Gain = 0.5 * (gain(L) + gain(R) - gain(parent)), where
+	// gain(·) = G^2/(H+λ2) when there is no L1, delta-step limit or bound
+	gain = 0.5 * (p.gainGivenOutput(leftGrad, leftHess, leftOut) +
+		p.gainGivenOutput(rightGrad, rightHess, rightOut) - parentGain)
+	return gain, leftOut, rightOut
+}
+
+// sampleFeatures returns a sorted random subset holding fraction of the
+// given features (at least one), or features itself when fraction ≤ 0 or
+// ≥ 1.
+func (p *Params) sampleFeatures(features []int, fraction float64) []int {
+	if fraction <= 0 || fraction >= 1 || len(features) == 0 {
+		return features
+	}
+	n := max(1, int(math.Round(fraction*float64(len(features)))))
+	picked := make([]int, 0, n)
+	for _, k := range p.Rand.Perm(len(features))[:n] {
+		picked = append(picked, features[k])
+	}
+	sort.Ints(picked)
+	return picked
+}
@@ -0,0 +1,100 @@
+// tree/refit.go
+package tree
+
+import "slices"
+
+// NumLeaves returns the number of leaves of the tree.
+func NumLeaves(n *Node) int {
+	if n.IsLeaf {
+		return 1
+	}
+	return NumLeaves(n.Left) + NumLeaves(n.Right)
+}
+
+// PredictLeaf returns the index of the leaf x falls into, with leaves
+// numbered from left to right as in LightGBM's model files.
+func PredictLeaf(root *Node, x []float64) int {
+	idx := 0
+	n := root
+	for !n.IsLeaf {
+		next := n.child(x)
+		if next == n.Right {
+			idx += NumLeaves(n.Left)
+		}
+		n = next
+	}
+	return idx
+}
+
+// leafOf returns the leaf x falls into.
+func leafOf(n *Node, x []float64) *Node {
+	for !n.IsLeaf {
+		n = n.child(x)
+	}
+	return n
+}
+
+// RefitLeaves keeps the structure of the tree but recomputes its leaf
+// values on new rows X with gradients grad and hessians hess: every leaf
+// some row reaches becomes decay*old + (1-decay)*new, new being the
+// regularized output for the sums of those rows. Linear leaves blend their
+// refitted models the same way. Other leaves keep their value. Under
+// p.MonotoneConstraints the leaf values are then clamped to the bounds of
+// boundMonotone, so the refitted tree keeps its monotonicity.
+func RefitLeaves(root *Node, X [][]float64, grad, hess []float64, p Params, decay float64) {
+	type sums struct {
+		grad, hess float64
+		rows       []int
+	}
+	leaves := make(map[*Node]*sums)
+	for i, x := range X {
+		leaf := leafOf(root, x)
+		s := leaves[leaf]
+		if s == nil {
+			s = &sums{}
+			leaves[leaf] = s
+		}
+		s.grad += grad[i]
+		s.hess += hess[i]
+		s.rows = append(s.rows, i)
+	}
+	for leaf, s := range leaves {
+		leaf.Value = decay*leaf.Value + (1-decay)*p.leafOutput(s.grad, s.hess)
+		if leaf.LinearFeatures == nil {
+			continue
+		}
+		if c, coeffs, ok := fitLinear(X, s.rows, grad, hess, leaf.LinearFeatures, p.LinearLambda); ok {
+			leaf.LinearConst = decay*leaf.LinearConst + (1-decay)*c
+			for k := range coeffs {
+				leaf.LinearCoeffs[k] = decay*leaf.LinearCoeffs[k] + (1-decay)*coeffs[k]
+			}
+		}
+	}
+	if slices.ContainsFunc(p.MonotoneConstraints, func(c int) bool { return c != 0 }) {
+		p.boundMonotone(root, unbounded)
+	}
+}
+
+// boundMonotone clamps the leaf values below n to c, splitting the bounds
+// top-down as childBounds does while growing: the children of a split on a
+// monotone feature get bounds meeting at the midpoint of their expected
+// values.
+func (p *Params) boundMonotone(n *Node, c bounds) {
+	if n.IsLeaf {
+		n.Value = min(max(n.Value, c.min), c.max)
+		return
+	}
+	left, right := c, c
+	if mono := p.monotone(n.FeatureIdx); mono != 0 && n.Categories == nil {
+		leftOut := min(max(ExpectedValue(n.Left), c.min), c.max)
+		rightOut := min(max(ExpectedValue(n.Right), c.min), c.max)
+		mid := (leftOut + rightOut) / 2
+		if mono > 0 {
+			left.max, right.min = mid, mid
+		} else {
+			left.min, right.max = mid, mid
+		}
+	}
+	p.boundMonotone(n.Left, left)
+	p.boundMonotone(n.Right, right)
+}
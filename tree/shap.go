@@ -0,0 +1,159 @@
+// tree/shap.go
+package tree
+
+// pathElement is one feature on the unique path of the TreeSHAP recursion.
+type pathElement struct {
+	featureIdx   int
+	zeroFraction float64
+	oneFraction  float64
+	pweight      float64
+}
+
+// TreeSHAP adds the exact SHAP values of the tree's prediction for x,
+// multiplied by scale, to phi. phi has one entry per feature plus a final
+// bias entry, which receives the tree's expected value. Node covers are the
+// background distribution (see childFractions).
+//
+// This is Algorithm 2 of Lundberg et al., "Consistent Individualized
+// Feature Attribution for Tree Ensembles", as implemented by LightGBM.
+func TreeSHAP(root *Node, x []float64, phi []float64, scale float64) {
+	phi[len(phi)-1] += scale * ExpectedValue(root)
+	if root.IsLeaf {
+		return
+	}
+	treeSHAP(root, x, phi, scale, nil, 0, 1, 1, -1)
+}
+
+// ExpectedValue returns the cover-weighted mean leaf value of the tree.
+func ExpectedValue(n *Node) float64 {
+	if n.IsLeaf {
+		return n.Value
+	}
+	l, r := childFractions(n.Left, n.Right)
+	return l*ExpectedValue(n.Left) + r*ExpectedValue(n.Right)
+}
+
+// childFractions returns the shares of their parent's cover that go to the
+// sibling nodes a and b, using sample counts when a cover is zero and an even split when
+// a count is zero too.
+func childFractions(a, b *Node) (float64, float64) {
+	if a.Cover > 0 && b.Cover > 0 {
+		total := a.Cover + b.Cover
+		return a.Cover / total, b.Cover / total
+	}
+	if a.Count > 0 && b.Count > 0 {
+		total := float64(a.Count + b.Count)
+		return float64(a.Count) / total, float64(b.Count) / total
+	}
+	return 0.5, 0.5
+}
+
+func treeSHAP(
+	n *Node,
+	x, phi []float64,
+	scale float64,
+	parentPath []pathElement,
+	uniqueDepth int,
+	parentZeroFraction, parentOneFraction float64,
+	parentFeatureIdx int,
+) {
+	// Extend the unique path
+	path := make([]pathElement, uniqueDepth+1)
+	copy(path, parentPath[:uniqueDepth])
+	extendPath(path, uniqueDepth, parentZeroFraction, parentOneFraction, parentFeatureIdx)
+
+	if n.IsLeaf {
+		for i := 1; i <= uniqueDepth; i++ {
+			w := unwoundPathSum(path, uniqueDepth, i)
+			el := path[i]
+			phi[el.featureIdx] += scale * w * (el.oneFraction - el.zeroFraction) * n.Value
+		}
+		return
+	}
+
+	hot := n.child(x)
+	cold := n.Left
+	if hot == n.Left {
+		cold = n.Right
+	}
+	hotZeroFraction, coldZeroFraction := childFractions(hot, cold)
+	incomingZeroFraction, incomingOneFraction := 1.0, 1.0
+
+	// If this feature was already split on along the path, undo that
+	// split so it can be redone here
+	pathIdx := 0
+	for ; pathIdx <= uniqueDepth; pathIdx++ {
+		if path[pathIdx].featureIdx == n.FeatureIdx {
+			break
+		}
+	}
+	if pathIdx != uniqueDepth+1 {
+		incomingZeroFraction = path[pathIdx].zeroFraction
+		incomingOneFraction = path[pathIdx].oneFraction
+		unwindPath(path, uniqueDepth, pathIdx)
+		uniqueDepth--
+	}
+
+	treeSHAP(hot, x, phi, scale, path, uniqueDepth+1,
+		hotZeroFraction*incomingZeroFraction, incomingOneFraction, n.FeatureIdx)
+	treeSHAP(cold, x, phi, scale, path, uniqueDepth+1,
+		coldZeroFraction*incomingZeroFraction, 0, n.FeatureIdx)
+}
+
+func extendPath(path []pathElement, uniqueDepth int, zeroFraction, oneFraction float64, featureIdx int) {
+	path[uniqueDepth] = pathElement{
+		featureIdx:   featureIdx,
+		zeroFraction: zeroFraction,
+		oneFraction:  oneFraction,
+	}
+	if uniqueDepth == 0 {
+		path[uniqueDepth].pweight = 1
+	}
+	d := float64(uniqueDepth + 1)
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		path[i+1].pweight += oneFraction * path[i].pweight * float64(i+1) / d
+		path[i].pweight = zeroFraction * path[i].pweight * float64(uniqueDepth-i) / d
+	}
+}
+
+func unwindPath(path []pathElement, uniqueDepth, pathIdx int) {
+	oneFraction := path[pathIdx].oneFraction
+	zeroFraction := path[pathIdx].zeroFraction
+	nextOnePortion := path[uniqueDepth].pweight
+	d := float64(uniqueDepth + 1)
+
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := path[i].pweight
+			path[i].pweight = nextOnePortion * d / (float64(i+1) * oneFraction)
+			nextOnePortion = tmp - path[i].pweight*zeroFraction*float64(uniqueDepth-i)/d
+		} else {
+			path[i].pweight = path[i].pweight * d / (zeroFraction * float64(uniqueDepth-i))
+		}
+	}
+
+	for i := pathIdx; i < uniqueDepth; i++ {
+		path[i].featureIdx = path[i+1].featureIdx
+		path[i].zeroFraction = path[i+1].zeroFraction
+		path[i].oneFraction = path[i+1].oneFraction
+	}
+}
+
+func unwoundPathSum(path []pathElement, uniqueDepth, pathIdx int) float64 {
+	oneFraction := path[pathIdx].oneFraction
+	zeroFraction := path[pathIdx].zeroFraction
+	nextOnePortion := path[uniqueDepth].pweight
+	d := float64(uniqueDepth + 1)
+
+	var total float64
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := nextOnePortion * d / (float64(i+1) * oneFraction)
+			total += tmp
+			nextOnePortion = path[i].pweight - tmp*zeroFraction*(float64(uniqueDepth-i)/d)
+		} else {
+			total += (path[i].pweight / zeroFraction) / (float64(uniqueDepth-i) / d)
+		}
+	}
+	return total
+}
@@ -3,217 +3,542 @@ package tree
 
 import (
 	"math"
+	"slices"
 )
 
 // Node represents one node in histogram-based regression tree.
 type Node struct {
-	FeatureIdx int    
-	Threshold  float64 
-	Left  *Node
-	Right *Node
-	Value  float64 
-	IsLeaf bool
-}
-
-// BuildHistogramTree fits a histogram-based regression tree to (X, grad, hess).
-//   - X: N×D feature matrix
-//   - grad: length-N slice of gradients
-//   - hess: length-N slice of hessians (for squared-error, hess[i] = 1)
-//   - depth: current depth (start at 0)
-//   - maxDepth: maximum depth allowed
-//   - minSamples: minimum number of samples to allow a split
-//   - numBins: number of bins to discretize each feature
+	FeatureIdx int
+	Threshold  float64
+	Left       *Node
+	Right      *Node
+	Value      float64
+	IsLeaf     bool
+
+	// DefaultLeft sends missing (NaN) values left. It is learned when the
+	// feature had missing values in training; otherwise NaN is routed as 0.
+	DefaultLeft bool
+
+	// Categories makes this a categorical split: it is a bitset of the
+	// category codes sent left, and every other value, NaN included, goes
+	// right. It is nil for numerical splits.
+	Categories []uint32
+
+	// Gain is the loss reduction of the split made at this node (0 for
+	// leaves); Count is the number of training samples that reached it and
+	// Cover the sum of their hessians.
+	Gain  float64
+	Count int
+	Cover float64
+
+	// A linear leaf outputs LinearConst + Σ LinearCoeffs[k]*x[LinearFeatures[k]]
+	// instead of Value, which it keeps for inputs where one of those
+	// features is NaN. LinearFeatures is nil for constant leaves.
+	LinearConst    float64
+	LinearFeatures []int
+	LinearCoeffs   []float64
+}
+
+// histBin accumulates gradient statistics for one bin of one feature.
+type histBin struct {
+	sumG  float64
+	sumH  float64
+	count int
+}
+
+// split describes the best split found for a node: rows whose bin of
+// feature feat is ≤ bin go left, and rows in the missing-value bin go left
+// iff defaultLeft. Categorical splits instead send the bins marked in
+// catLeft left. leftOut and rightOut are the children's outputs.
+type split struct {
+	feat        int
+	bin         int
+	gain        float64
+	defaultLeft bool
+	catLeft     []bool
+
+	leftOut, rightOut float64
+}
+
+// builder holds the state shared by every node of the tree being grown.
+// features are the features the tree may split on and groups the columns
+// of the Dataset that hold them. interactions[g][j] marks the features of
+// interaction constraint g.
+type builder struct {
+	ds           *Dataset
+	grad         []float64
+	hess         []float64
+	p            Params
+	features     []int
+	groups       []int
+	interactions [][]bool
+}
+
+// newBuilder returns a builder for one tree, drawing its features when
+// p.FeatureFraction is in use.
+func newBuilder(ds *Dataset, grad, hess []float64, p Params) *builder {
+	all := make([]int, ds.NumFeatures)
+	for j := range all {
+		all[j] = j
+	}
+	b := &builder{ds: ds, grad: grad, hess: hess, p: p}
+	b.features = b.p.sampleFeatures(all, p.FeatureFraction)
+	seen := make([]bool, len(ds.groups))
+	for _, j := range b.features {
+		if g := ds.group[j]; !seen[g] {
+			seen[g] = true
+			b.groups = append(b.groups, g)
+		}
+	}
+	for _, group := range p.InteractionConstraints {
+		in := make([]bool, ds.NumFeatures)
+		for _, j := range group {
+			if j >= 0 && j < ds.NumFeatures {
+				in[j] = true
+			}
+		}
+		b.interactions = append(b.interactions, in)
+	}
+	return b
+}
+
+// allowedFeatures returns the features of the tree that a node may split
+// on under the interaction constraints, given the features split on along
+// its path from the root.
+func (b *builder) allowedFeatures(used []int) []int {
+	if b.interactions == nil {
+		return b.features
+	}
+	var allowed []int
+	for _, j := range b.features {
+		for _, in := range b.interactions {
+			if in[j] && holdsAll(in, used) {
+				allowed = append(allowed, j)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+// holdsAll reports whether the feature set in contains every feature of used.
+func holdsAll(in []bool, used []int) bool {
+	for _, j := range used {
+		if !in[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathWith returns the features used on a path extended by a split on
+// feature j, without modifying used.
+func pathWith(used []int, j int) []int {
+	if slices.Contains(used, j) {
+		return used
+	}
+	return append(used[:len(used):len(used)], j)
+}
+
+// BuildHistogramTree fits a histogram-based regression tree to (ds, grad, hess),
+// growing it depth-wise up to p.MaxDepth.
+//   - ds: pre-binned feature matrix
+//   - rows: indices of the rows of ds to fit
+//   - grad: gradients, indexed by row of ds
+//   - hess: hessians, indexed by row of ds (for squared-error, hess[i] = 1)
+//   - p: growth limits and regularization
 func BuildHistogramTree(
-	X [][]float64,
+	ds *Dataset,
+	rows []int,
 	grad []float64,
 	hess []float64,
-	depth, maxDepth, minSamples, numBins int,
+	p Params,
 ) *Node {
-	N := len(grad)
-	if N == 0 {
+	if len(rows) == 0 {
 		return &Node{IsLeaf: true, Value: 0.0}
 	}
+	b := newBuilder(ds, grad, hess, p)
+	hists := b.buildHistograms(rows)
+	return b.growDepthWise(rows, hists, 0, unbounded, nil)
+}
 
-	// Compute total sum of gradients and hessians for this node
-	var sumGrad, sumHess float64
-	for i := 0; i < N; i++ {
-		sumGrad += grad[i]
-		sumHess += hess[i]
-	}
+// growDepthWise recursively splits a node whose histograms are already
+// known and whose leaf outputs must stay within c. used lists the features
+// split on above the node.
+func (b *builder) growDepthWise(rows []int, hists [][]histBin, depth int, c bounds, used []int) *Node {
+	N := len(rows)
+	sumGrad, sumHess := b.sums(rows)
 
 	// If max depth reached or too few samples, make a leaf
-	if depth >= maxDepth || N <= minSamples {
-		leafValue := -sumGrad / (sumHess + 1e-3)
-		return &Node{IsLeaf: true, Value: leafValue}
-	}
-
-	D := len(X[0]) 
-	
-	// Step 1: For each feature j, compute min and max over samples
-	minVals := make([]float64, D)
-	maxVals := make([]float64, D)
-	for j := 0; j < D; j++ {
-		minVals[j] = X[0][j]
-		maxVals[j] = X[0][j]
-		for i := 1; i < N; i++ {
-			v := X[i][j]
-			if v < minVals[j] {
-				minVals[j] = v
+	if depth >= b.p.MaxDepth || N <= b.p.MinSamples {
+		leaf := b.leafNode(sumGrad, sumHess, N, c)
+		b.fitLinearLeaf(leaf, rows, used)
+		return leaf
+	}
+
+	best := b.findBestSplit(hists, sumGrad, sumHess, N, c, used)
+
+	// If no valid split found, make a leaf
+	if best.feat < 0 {
+		leaf := b.leafNode(sumGrad, sumHess, N, c)
+		b.fitLinearLeaf(leaf, rows, used)
+		return leaf
+	}
+
+	leftRows, rightRows := b.partitionRows(rows, best)
+	leftHists, rightHists := b.childHistograms(hists, leftRows, rightRows)
+	leftBounds, rightBounds := b.childBounds(best, c)
+	used = pathWith(used, best.feat)
+
+	// Recurse
+	leftChild := b.growDepthWise(leftRows, leftHists, depth+1, leftBounds, used)
+	rightChild := b.growDepthWise(rightRows, rightHists, depth+1, rightBounds, used)
+
+	node := b.splitNode(best, N, sumHess)
+	node.Left = leftChild
+	node.Right = rightChild
+	return node
+}
+
+// leafNode returns a leaf for count samples with the given sums, its
+// output clamped to c.
+func (b *builder) leafNode(sumGrad, sumHess float64, count int, c bounds) *Node {
+	return &Node{
+		IsLeaf: true,
+		Value:  b.p.boundedOutput(sumGrad, sumHess, c),
+		Count:  count,
+		Cover:  sumHess,
+	}
+}
+
+// splitNode returns the internal node for split s of count samples whose
+// hessians sum to cover. It splits at the upper bound of bin s.bin, so raw
+// values route the same way their bins did during training. Features without
+// a missing-value bin send NaN wherever 0 goes.
+func (b *builder) splitNode(s split, count int, cover float64) *Node {
+	if s.catLeft != nil {
+		return &Node{
+			FeatureIdx: s.feat,
+			IsLeaf:     false,
+			Categories: b.categoryBitset(s.feat, s.catLeft),
+			Gain:       s.gain,
+			Count:      count,
+			Cover:      cover,
+		}
+	}
+	threshold := b.ds.BinUpperBounds[s.feat][s.bin]
+	if math.IsInf(threshold, 1) {
+		// Missing-vs-present split; keep the threshold finite so the
+		// tree still serializes
+		threshold = math.MaxFloat64
+	}
+	defaultLeft := s.defaultLeft
+	if b.ds.MissingBin(s.feat) < 0 {
+		defaultLeft = 0 <= threshold
+	}
+	return &Node{
+		FeatureIdx:  s.feat,
+		Threshold:   threshold,
+		IsLeaf:      false,
+		DefaultLeft: defaultLeft,
+		Gain:        s.gain,
+		Count:       count,
+		Cover:       cover,
+	}
+}
+
+// childBounds returns the output bounds of the children of split s of a
+// node bounded by c: for a monotone feature they meet at the midpoint of
+// the children's outputs, otherwise both inherit c.
+func (b *builder) childBounds(s split, c bounds) (left, right bounds) {
+	left, right = c, c
+	mono := b.p.monotone(s.feat)
+	if mono == 0 || s.catLeft != nil {
+		return left, right
+	}
+	mid := (s.leftOut + s.rightOut) / 2
+	if mono > 0 {
+		left.max, right.min = mid, mid
+	} else {
+		left.min, right.max = mid, mid
+	}
+	return left, right
+}
+
+// sums returns the total gradient and hessian over rows.
+func (b *builder) sums(rows []int) (sumGrad, sumHess float64) {
+	for _, i := range rows {
+		sumGrad += b.grad[i]
+		sumHess += b.hess[i]
+	}
+	return sumGrad, sumHess
+}
+
+// partitionRows splits rows into those that go left under s and the rest.
+func (b *builder) partitionRows(rows []int, s split) (left, right []int) {
+	left = make([]int, 0, len(rows))
+	right = make([]int, 0, len(rows))
+	missingBin := b.ds.MissingBin(s.feat)
+	for _, i := range rows {
+		bin := b.ds.Bin(i, s.feat)
+		if s.catLeft != nil {
+			if s.catLeft[bin] {
+				left = append(left, i)
+			} else {
+				right = append(right, i)
 			}
-			if v > maxVals[j] {
-				maxVals[j] = v
+		} else if bin == missingBin && missingBin >= 0 {
+			if s.defaultLeft {
+				left = append(left, i)
+			} else {
+				right = append(right, i)
 			}
+		} else if bin <= s.bin {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
 		}
 	}
+	return left, right
+}
 
-	// Step 2: Build histograms and find best split
-	type histBin struct {
-		sumG  float64
-		sumH  float64
-		count int
+// childHistograms applies the histogram subtraction trick: only the smaller
+// child's histograms are built from its rows, the larger child's are derived
+// by subtracting them from the parent's.
+func (b *builder) childHistograms(
+	parent [][]histBin,
+	leftRows, rightRows []int,
+) (left, right [][]histBin) {
+	if len(leftRows) <= len(rightRows) {
+		left = b.buildHistograms(leftRows)
+		right = subtractHistograms(parent, left)
+	} else {
+		right = b.buildHistograms(rightRows)
+		left = subtractHistograms(parent, right)
 	}
+	return left, right
+}
 
-	bestGain := math.Inf(-1)
-	bestFeat := -1
-	bestBinIdx := -1
+// buildHistograms accumulates one histogram per feature over the given rows,
+// one pass per stored column, unbundling bundled features afterwards.
+// Blocks of columns are handled by up to p.NumThreads goroutines; each
+// column is still summed in row order, so the result does not depend on
+// their number. Features outside the tree's columns are left nil.
+func (b *builder) buildHistograms(rows []int) [][]histBin {
+	ds := b.ds
+	hists := make([][]histBin, ds.NumFeatures)
+	parallelFor(len(b.groups), b.p.NumThreads, func(lo, hi int) {
+		for _, g := range b.groups[lo:hi] {
+			group := &ds.groups[g]
+			hist := make([]histBin, group.numBins)
+			for _, i := range rows {
+				bin := group.col.at(i)
+				hist[bin].sumG += b.grad[i]
+				hist[bin].sumH += b.hess[i]
+				hist[bin].count++
+			}
+			ds.unbundleHistogram(g, hist, hists)
+		}
+	})
+	return hists
+}
 
-	// For each feature, build a histogram of numBins bins
-	for j := 0; j < D; j++ {
-		minVal := minVals[j]
-		maxVal := maxVals[j]
-		width := (maxVal - minVal) / float64(numBins)
-		if width == 0 {
-			// All values identical → cannot split on this feature
-			continue
+// subtractHistograms returns parent - child bin by bin, i.e. the histograms
+// of the sibling of child.
+func subtractHistograms(parent, child [][]histBin) [][]histBin {
+	out := make([][]histBin, len(parent))
+	for j := range parent {
+		hist := make([]histBin, len(parent[j]))
+		for b := range hist {
+			hist[b].sumG = parent[j][b].sumG - child[j][b].sumG
+			hist[b].sumH = parent[j][b].sumH - child[j][b].sumH
+			hist[b].count = parent[j][b].count - child[j][b].count
 		}
+		out[j] = hist
+	}
+	return out
+}
+
+// findBestSplit scans every bin boundary of every numerical feature, and
+// the category orderings of every categorical one, among the features
+// sampled for this split that the interaction constraints allow after the
+// features used on its path, and returns the split with the highest gain.
+// With p.ExtraTrees only one random threshold per feature is tried. The
+// features are searched by up to p.NumThreads goroutines, and ties go to
+// the lowest feature whatever their number. The returned split has feat -1
+// if no split satisfies the sample and hessian limits, respects the
+// monotone constraints and output bounds c and beats MinGainToSplit.
+func (b *builder) findBestSplit(
+	hists [][]histBin,
+	totalGrad, totalHess float64,
+	totalCount int,
+	c bounds,
+	used []int,
+) split {
+	parentGain := b.p.gainGivenOutput(totalGrad, totalHess, b.p.boundedOutput(totalGrad, totalHess, c))
+	features := b.p.sampleFeatures(b.allowedFeatures(used), b.p.FeatureFractionByNode)
 
-		// Initialize histogram bins
-		hist := make([]histBin, numBins)
-		for i := 0; i < N; i++ {
-			v := X[i][j]
-			bin := int((v - minVal) / width)
-			if bin < 0 {
-				bin = 0
-			} else if bin >= numBins {
-				bin = numBins - 1
+	// Random thresholds are drawn up front, in feature order, so that
+	// they do not depend on how the features are shared out
+	randThresholds := make([]int, len(features))
+	for f, j := range features {
+		randThresholds[f] = b.randThreshold(j, hists[j])
+	}
+
+	bests := make([]split, len(features))
+	parallelFor(len(features), b.p.NumThreads, func(lo, hi int) {
+		for f := lo; f < hi; f++ {
+			j := features[f]
+			bests[f] = split{feat: -1, bin: -1, gain: math.Inf(-1)}
+			if b.ds.IsCategorical(j) {
+				b.findCategoricalSplit(j, hists[j], totalGrad, totalHess, totalCount, parentGain, c, randThresholds[f], &bests[f])
+			} else {
+				b.findNumericalSplit(j, hists[j], totalGrad, totalHess, totalCount, parentGain, c, randThresholds[f], &bests[f])
 			}
-			hist[bin].sumG += grad[i]
-			hist[bin].sumH += hess[i]
-			hist[bin].count++
 		}
+	})
 
-		// Prefix sums to get left-side accumulations
-		leftGradSum := make([]float64, numBins)
-		leftHessSum := make([]float64, numBins)
-		leftCount := make([]int, numBins)
-
-		var cumG, cumH float64
-		var cumC int
-		for b := 0; b < numBins; b++ {
-			cumG += hist[b].sumG
-			cumH += hist[b].sumH
-			cumC += hist[b].count
-			leftGradSum[b] = cumG
-			leftHessSum[b] = cumH
-			leftCount[b] = cumC
+	best := split{feat: -1, bin: -1, gain: math.Inf(-1)}
+	for _, s := range bests {
+		if s.feat >= 0 && s.gain > best.gain {
+			best = s
 		}
+	}
+	return best
+}
+
+// randThreshold draws the one threshold extra trees try for feature j
+// (see findNumericalSplit and findCategoricalSplit), or returns -1 when
+// p.ExtraTrees is off or the feature cannot be split.
+func (b *builder) randThreshold(j int, hist []histBin) int {
+	if !b.p.ExtraTrees {
+		return -1
+	}
+	if b.ds.IsCategorical(j) {
+		return b.randCategoricalThreshold(j, hist)
+	}
+	numBins := len(b.ds.BinUpperBounds[j])
+	maxBin := numBins - 2
+	if missingBin := b.ds.MissingBin(j); missingBin >= 0 && hist[missingBin].count > 0 {
+		maxBin = numBins - 1
+	}
+	if maxBin < 0 {
+		return -1
+	}
+	return b.p.Rand.Intn(maxBin + 1)
+}
+
+// findNumericalSplit looks for a threshold of numerical feature j that
+// beats best. For features with missing values, each boundary is tried with
+// the missing-value bin sent right and sent left. randBin ≥ 0 restricts
+// the search to that boundary.
+func (b *builder) findNumericalSplit(
+	j int,
+	hist []histBin,
+	totalGrad, totalHess float64,
+	totalCount int,
+	parentGain float64,
+	c bounds,
+	randBin int,
+	best *split,
+) {
+	numBins := len(b.ds.BinUpperBounds[j])
+	missingBin := b.ds.MissingBin(j)
+
+	var missing histBin
+	if missingBin >= 0 {
+		missing = hist[missingBin]
+	}
+	if numBins < 2 && missing.count == 0 {
+		// All values in one bin → cannot split on this feature
+		return
+	}
 
-		totalGrad := sumGrad
-		totalHess := sumHess
-		totalCount := N
+	for _, defaultLeft := range []bool{false, true} {
+		if defaultLeft && missing.count == 0 {
+			continue
+		}
 
-		// Evaluate splits at each bin boundary b (left = bins ≤ b)
-		for b := 0; b < numBins-1; b++ {
-			G_L := leftGradSum[b]
-			H_L := leftHessSum[b]
-			C_L := leftCount[b]
+		// Evaluate splits at each bin boundary k (left = bins ≤ k).
+		// With missing values sent right, k may also be the last
+		// numeric bin, separating missing from present values.
+		var G_L, H_L float64
+		var C_L int
+		if defaultLeft {
+			G_L, H_L, C_L = missing.sumG, missing.sumH, missing.count
+		}
+		lastBin := numBins - 2
+		if !defaultLeft && missing.count > 0 {
+			lastBin = numBins - 1
+		}
+		for k := 0; k <= lastBin; k++ {
+			G_L += hist[k].sumG
+			H_L += hist[k].sumH
+			C_L += hist[k].count
 
 			G_R := totalGrad - G_L
 			H_R := totalHess - H_L
 			C_R := totalCount - C_L
 
+			if randBin >= 0 && k != randBin {
+				continue
+			}
 			// Skip if either side too small
-			if C_L < minSamples || C_R < minSamples {
+			if C_L < b.p.MinSamples || C_R < b.p.MinSamples {
 				continue
 			}
-
-			// Gain = 0.5 * (G_L^2/(H_L+λ) + G_R^2/(H_R+λ) - G_total^2/(H_total+λ))
-			le := (G_L * G_L) / (H_L + 1e-3)
-			re := (G_R * G_R) / (H_R + 1e-3)
-			mega := (totalGrad * totalGrad) / (totalHess + 1e-3)
-			gain := 0.5 * (le + re - mega)
-
-			if gain > bestGain {
-				bestGain = gain
-				bestFeat = j
-				bestBinIdx = b
+			if H_L < b.p.MinSumHessianInLeaf || H_R < b.p.MinSumHessianInLeaf {
+				continue
 			}
-		}
-	}
 
-	// If no valid split found, make a leaf
-	if bestFeat < 0 {
-		leafValue := -sumGrad / (sumHess + 1e-3)
-		return &Node{IsLeaf: true, Value: leafValue}
-	}
-
-	// Compute float threshold for the best split
-	minVal := minVals[bestFeat]
-	maxVal := maxVals[bestFeat]
-	width := (maxVal - minVal) / float64(numBins)
-	// Split at boundary between bin bestBinIdx and bestBinIdx+1
-	threshold := minVal + width*float64(bestBinIdx+1)
-
-	// Partition samples into left/right by comparing to threshold
-	leftIdx := make([]int, 0, N)
-	rightIdx := make([]int, 0, N)
-	for i := 0; i < N; i++ {
-		if X[i][bestFeat] <= threshold {
-			leftIdx = append(leftIdx, i)
-		} else {
-			rightIdx = append(rightIdx, i)
+			gain, leftOut, rightOut := b.p.splitGain(G_L, H_L, G_R, H_R, parentGain, c, b.p.monotone(j))
+			if gain > b.p.MinGainToSplit && gain > best.gain {
+				*best = split{
+					feat: j, bin: k, gain: gain, defaultLeft: defaultLeft,
+					leftOut: leftOut, rightOut: rightOut,
+				}
+			}
 		}
 	}
+}
 
-	// Build child datasets
-	Xleft := make([][]float64, len(leftIdx))
-	gradLeft := make([]float64, len(leftIdx))
-	hessLeft := make([]float64, len(leftIdx))
-	for i, idx := range leftIdx {
-		Xleft[i] = X[idx]
-		gradLeft[i] = grad[idx]
-		hessLeft[i] = hess[idx]
-	}
-
-	Xright := make([][]float64, len(rightIdx))
-	gradRight := make([]float64, len(rightIdx))
-	hessRight := make([]float64, len(rightIdx))
-	for i, idx := range rightIdx {
-		Xright[i] = X[idx]
-		gradRight[i] = grad[idx]
-		hessRight[i] = hess[idx]
+// PredictTree traverses the tree to return a prediction for a single feature vector x.
+func PredictTree(node *Node, x []float64) float64 {
+	if node.IsLeaf {
+		return node.output(x)
 	}
+	return PredictTree(node.child(x), x)
+}
 
-	// Recurse
-	leftChild := BuildHistogramTree(Xleft, gradLeft, hessLeft, depth+1, maxDepth, minSamples, numBins)
-	rightChild := BuildHistogramTree(Xright, gradRight, hessRight, depth+1, maxDepth, minSamples, numBins)
-
-	return &Node{
-		FeatureIdx: bestFeat,
-		Threshold:  threshold,
-		Left:       leftChild,
-		Right:      rightChild,
-		IsLeaf:     false,
+// ScaleLeaves multiplies every leaf output of the tree by factor.
+func ScaleLeaves(n *Node, factor float64) {
+	if n.IsLeaf {
+		n.Value *= factor
+		n.LinearConst *= factor
+		for k := range n.LinearCoeffs {
+			n.LinearCoeffs[k] *= factor
+		}
+		return
 	}
+	ScaleLeaves(n.Left, factor)
+	ScaleLeaves(n.Right, factor)
 }
 
-// PredictTree traverses the tree to return a prediction for a single feature vector x.
-func PredictTree(node *Node, x []float64) float64 {
-	if node.IsLeaf {
-		return node.Value
+// child returns the child of an internal node that x is routed to. Missing
+// (NaN) values follow the node's default direction.
+func (n *Node) child(x []float64) *Node {
+	v := x[n.FeatureIdx]
+	if n.Categories != nil {
+		return n.categoricalChild(v)
+	}
+	if math.IsNaN(v) {
+		if n.DefaultLeft {
+			return n.Left
+		}
+		return n.Right
 	}
-	if x[node.FeatureIdx] <= node.Threshold {
-		return PredictTree(node.Left, x)
+	if v <= n.Threshold {
+		return n.Left
 	}
-	return PredictTree(node.Right, x)
+	return n.Right
 }
@@ -0,0 +1,22 @@
+package util
+
+// Argmax returns the index of the largest value in vals.
+func Argmax(vals []float64) int {
+	best := 0
+	for i, v := range vals {
+		if v > vals[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// ClassIndex turns one target's prediction into a class index in
+// [0, numClasses-1]: multiclass predictions (one probability per class) pick
+// the most likely class, single regression outputs are rounded and clamped.
+func ClassIndex(pred []float64, numClasses int) int {
+	if len(pred) > 1 {
+		return Argmax(pred)
+	}
+	return Clamp(pred[0], numClasses)
+}
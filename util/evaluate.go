@@ -2,7 +2,6 @@ package util
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/jesee-kuya/LightGBM/booster"
 	"github.com/jesee-kuya/LightGBM/preprocess"
@@ -26,23 +25,20 @@ func Evaluate(
 		pred := boost.Predict(Xval[i])
 		trueRow := Yval[i]
 		for j := range numTargets {
-			pi := max(int(math.Round(pred[j])), 0)
-			var maxIdx int
+			var numClasses int
 			switch j {
 			case 0:
-				maxIdx = len(pre.ClinicianClasses()) - 1
+				numClasses = len(pre.ClinicianClasses())
 			case 1:
-				maxIdx = len(pre.GPT4Classes()) - 1
+				numClasses = len(pre.GPT4Classes())
 			case 2:
-				maxIdx = len(pre.LLAMAClasses()) - 1
+				numClasses = len(pre.LLAMAClasses())
 			case 3:
-				maxIdx = len(pre.GEMINIClasses()) - 1
+				numClasses = len(pre.GEMINIClasses())
 			case 4:
-				maxIdx = len(pre.DDXClasses()) - 1
-			}
-			if pi > maxIdx {
-				pi = maxIdx
+				numClasses = len(pre.DDXClasses())
 			}
+			pi := ClassIndex(pred[j], numClasses)
 			if pi == int(trueRow[j]) {
 				correct[j]++
 			}
@@ -40,11 +40,11 @@ func WritePredictions(
 
 	for i, rec := range records {
 		pred := boost.Predict(Xall[i])
-		clinIdx := util.Clamp(pred[0], len(clinLabels))
-		gpt4Idx := util.Clamp(pred[1], len(gpt4Labels))
-		llamaIdx := util.Clamp(pred[2], len(llamaLabels))
-		geminiIdx := util.Clamp(pred[3], len(geminiLabels))
-		ddxIdx := util.Clamp(pred[4], len(ddxLabels))
+		clinIdx := util.ClassIndex(pred[0], len(clinLabels))
+		gpt4Idx := util.ClassIndex(pred[1], len(gpt4Labels))
+		llamaIdx := util.ClassIndex(pred[2], len(llamaLabels))
+		geminiIdx := util.ClassIndex(pred[3], len(geminiLabels))
+		ddxIdx := util.ClassIndex(pred[4], len(ddxLabels))
 
 		row := []string{
 			rec.ID,